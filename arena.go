@@ -0,0 +1,53 @@
+package argh
+
+// arenaChunkSize is the number of values, or of slices of values, for
+// which an arena allocates room at once.
+const arenaChunkSize = 8
+
+// arena hands out the values of a parse from chunks allocated
+// together, so that the nodes of a parse take few allocations. The
+// arenas of a Parser are dropped by Reset, so that the ParseTrees of
+// separate calls to Parse never share memory.
+type arena[T any] struct {
+	chunk []T
+}
+
+// alloc returns a pointer to a new zero value.
+func (a *arena[T]) alloc() *T {
+	if len(a.chunk) == 0 {
+		a.chunk = make([]T, arenaChunkSize)
+	}
+
+	v := &a.chunk[0]
+	a.chunk = a.chunk[1:]
+
+	return v
+}
+
+// slice returns an empty slice with room for n values, which once
+// full is reallocated when appended to rather than overwriting the
+// values of other slices.
+func (a *arena[T]) slice(n int) []T {
+	if len(a.chunk) < n {
+		a.chunk = make([]T, n*arenaChunkSize)
+	}
+
+	s := a.chunk[:0:n]
+	a.chunk = a.chunk[n:]
+
+	return s
+}
+
+func (p *Parser) newFlag(name string) *Flag {
+	node := p.flagArena.alloc()
+	node.Name = name
+
+	return node
+}
+
+func (p *Parser) newIdent(lit string) *Ident {
+	node := p.identArena.alloc()
+	node.Literal = lit
+
+	return node
+}
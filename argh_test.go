@@ -61,129 +61,180 @@ func BenchmarkStdlibFlag(b *testing.B) {
 	}
 }
 
-func BenchmarkArgh(b *testing.B) {
-	for i := 0; i < b.N; i++ {
-		func() {
-			var (
-				okFlag  *bool
-				durFlag *time.Duration
-				f64Flag *float64
-				iFlag   *int
-				i64Flag *int64
-				sFlag   *string
-				uFlag   *uint
-				u64Flag *uint64
-			)
+// benchFlags holds the values set by the flag handlers of the
+// config returned by newBenchParserConfig.
+type benchFlags struct {
+	okFlag  *bool
+	durFlag *time.Duration
+	f64Flag *float64
+	iFlag   *int
+	i64Flag *int64
+	sFlag   *string
+	uFlag   *uint
+	u64Flag *uint64
+}
+
+func (bf *benchFlags) String() string {
+	return fmt.Sprint(
+		"okFlag", ptrFrom(bf.okFlag),
+		"durFlag", ptrFrom(bf.durFlag),
+		"f64Flag", ptrFrom(bf.f64Flag),
+		"iFlag", ptrFrom(bf.iFlag),
+		"i64Flag", ptrFrom(bf.i64Flag),
+		"sFlag", ptrFrom(bf.sFlag),
+		"uFlag", ptrFrom(bf.uFlag),
+		"u64Flag", ptrFrom(bf.u64Flag),
+	)
+}
 
-			pCfg := argh.NewParserConfig()
-			pCfg.Prog = &argh.CommandConfig{
-				Flags: &argh.Flags{
-					Map: map[string]argh.FlagConfig{
-						"ok": {
-							On: func(fl argh.Flag) error {
-								okFlag = ptrTo(true)
-								return nil
-							},
-						},
-						"dur": {
-							NValue: 1,
-							On: func(fl argh.Flag) error {
-								if v, ok := fl.Values["0"]; ok {
-									if pt, err := time.ParseDuration(v); err != nil {
-										durFlag = ptrTo(pt)
-									}
-								}
-								return nil
-							},
-						},
-						"f64": {
-							NValue: 1,
-							On: func(fl argh.Flag) error {
-								if v, ok := fl.Values["0"]; ok {
-									if f, err := strconv.ParseFloat(v, 64); err == nil {
-										f64Flag = ptrTo(f)
-									}
-								}
-								return nil
-							},
-						},
-						"i": {
-							NValue: 1,
-							On: func(fl argh.Flag) error {
-								if v, ok := fl.Values["0"]; ok {
-									if i, err := strconv.ParseInt(v, 10, 64); err == nil {
-										iFlag = ptrTo(int(i))
-									}
-								}
-								return nil
-							},
-						},
-						"i64": {
-							NValue: 1,
-							On: func(fl argh.Flag) error {
-								if v, ok := fl.Values["0"]; ok {
-									if i, err := strconv.ParseInt(v, 10, 64); err == nil {
-										i64Flag = ptrTo(i)
-									}
-								}
-								return nil
-							},
-						},
-						"s": {
-							NValue: 1,
-							On: func(fl argh.Flag) error {
-								if v, ok := fl.Values["0"]; ok {
-									sFlag = ptrTo(v)
-								}
-								return nil
-							},
-						},
-						"u": {
-							NValue: 1,
-							On: func(fl argh.Flag) error {
-								if v, ok := fl.Values["0"]; ok {
-									if u, err := strconv.ParseUint(v, 10, 64); err == nil {
-										uFlag = ptrTo(uint(u))
-									}
-								}
-								return nil
-							},
-						},
-						"u64": {
-							NValue: 1,
-							On: func(fl argh.Flag) error {
-								if v, ok := fl.Values["0"]; ok {
-									if u, err := strconv.ParseUint(v, 10, 64); err == nil {
-										u64Flag = ptrTo(u)
-									}
-								}
-								return nil
-							},
-						},
+var benchArghArgs = []string{
+	"prog",
+	"--ok",
+	"--dur", "42h42m10s",
+	"--f64", "4242424242.42",
+	"-i", "-42",
+	"--i64", "-4242424242",
+	"-s", "the answer",
+	"-u", "42",
+	"--u64", "4242424242",
+}
+
+func newBenchParserConfig(bf *benchFlags) *argh.ParserConfig {
+	pCfg := argh.NewParserConfig()
+	pCfg.NegativeNumbers = argh.NegativeNumbersAsValues
+	pCfg.Prog = &argh.CommandConfig{
+		Flags: &argh.Flags{
+			Map: map[string]argh.FlagConfig{
+				"ok": {
+					On: func(fl argh.Flag) error {
+						bf.okFlag = ptrTo(true)
+						return nil
+					},
+				},
+				"dur": {
+					NValue: 1,
+					On: func(fl argh.Flag) error {
+						if v, ok := fl.Values["0"]; ok {
+							if pt, err := time.ParseDuration(v); err != nil {
+								bf.durFlag = ptrTo(pt)
+							}
+						}
+						return nil
+					},
+				},
+				"f64": {
+					NValue: 1,
+					On: func(fl argh.Flag) error {
+						if v, ok := fl.Values["0"]; ok {
+							if f, err := strconv.ParseFloat(v, 64); err == nil {
+								bf.f64Flag = ptrTo(f)
+							}
+						}
+						return nil
 					},
 				},
-			}
+				"i": {
+					NValue: 1,
+					On: func(fl argh.Flag) error {
+						if v, ok := fl.Values["0"]; ok {
+							if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+								bf.iFlag = ptrTo(int(i))
+							}
+						}
+						return nil
+					},
+				},
+				"i64": {
+					NValue: 1,
+					On: func(fl argh.Flag) error {
+						if v, ok := fl.Values["0"]; ok {
+							if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+								bf.i64Flag = ptrTo(i)
+							}
+						}
+						return nil
+					},
+				},
+				"s": {
+					NValue: 1,
+					On: func(fl argh.Flag) error {
+						if v, ok := fl.Values["0"]; ok {
+							bf.sFlag = ptrTo(v)
+						}
+						return nil
+					},
+				},
+				"u": {
+					NValue: 1,
+					On: func(fl argh.Flag) error {
+						if v, ok := fl.Values["0"]; ok {
+							if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+								bf.uFlag = ptrTo(uint(u))
+							}
+						}
+						return nil
+					},
+				},
+				"u64": {
+					NValue: 1,
+					On: func(fl argh.Flag) error {
+						if v, ok := fl.Values["0"]; ok {
+							if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+								bf.u64Flag = ptrTo(u)
+							}
+						}
+						return nil
+					},
+				},
+			},
+		},
+	}
 
-			_, _ = argh.ParseArgs([]string{
-				"--ok",
-				"--dur", "42h42m10s",
-				"--f64", "4242424242.42",
-				"-i", "-42",
-				"--i64", "-4242424242",
-				"-s", "the answer",
-				"-u", "42",
-				"--u64", "4242424242",
-			}, pCfg)
-			_ = fmt.Sprint(
-				"okFlag", ptrFrom(okFlag),
-				"durFlag", ptrFrom(durFlag),
-				"f64Flag", ptrFrom(f64Flag),
-				"iFlag", ptrFrom(iFlag),
-				"i64Flag", ptrFrom(i64Flag),
-				"sFlag", ptrFrom(sFlag),
-				"uFlag", ptrFrom(uFlag),
-				"u64Flag", ptrFrom(u64Flag),
-			)
+	return pCfg
+}
+
+func BenchmarkArgh(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		func() {
+			bf := &benchFlags{}
+			pCfg := newBenchParserConfig(bf)
+
+			_, _ = argh.ParseArgs(benchArghArgs, pCfg)
+			_ = bf.String()
 		}()
 	}
 }
+
+func BenchmarkArghParserReuse(b *testing.B) {
+	bf := &benchFlags{}
+	p := argh.NewParser(newBenchParserConfig(bf))
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Parse(benchArghArgs); err != nil {
+			b.Fatal(err)
+		}
+
+		_ = bf.String()
+	}
+}
+
+func BenchmarkArghCompiled(b *testing.B) {
+	bf := &benchFlags{}
+
+	cp, err := argh.Compile(newBenchParserConfig(bf))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := cp.Parse(benchArghArgs); err != nil {
+			b.Fatal(err)
+		}
+
+		_ = bf.String()
+	}
+}
@@ -6,13 +6,11 @@ package argh
 func ToAST(parseTree []Node) []Node {
 	ret := []Node{}
 
-	for i, node := range parseTree {
-		tracef("ToAST i=%d node type=%T", i, node)
-
+	for _, node := range parseTree {
 		switch v := node.(type) {
 		case *ArgDelimiter:
 			continue
-		case *StopFlag:
+		case *ListTerminator:
 			continue
 		case *Assign:
 			ret = append(ret, v)
@@ -35,9 +33,12 @@ func ToAST(parseTree []Node) []Node {
 			ret = append(
 				ret,
 				&Flag{
-					Name:   v.Name,
-					Values: v.Values,
-					Nodes:  astNodes,
+					Name:      v.Name,
+					Values:    v.Values,
+					Nodes:     astNodes,
+					Negated:   v.Negated,
+					ValueList: v.ValueList,
+					Pairs:     v.Pairs,
 				})
 
 			continue
@@ -51,9 +52,11 @@ func ToAST(parseTree []Node) []Node {
 			ret = append(
 				ret,
 				&Command{
-					Name:   v.Name,
-					Values: v.Values,
-					Nodes:  astNodes,
+					Name:      v.Name,
+					Values:    v.Values,
+					Nodes:     astNodes,
+					ValueList: v.ValueList,
+					Merged:    v.Merged,
 				})
 
 			continue
@@ -0,0 +1,169 @@
+package argh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Catalogue provides the text of the messages argh produces so that
+// they may be translated. Messages are formatted as by fmt.Sprintf,
+// with explicit argument indexes such as %[2]q in the default English
+// catalogue so that translations may reorder arguments. Arguments a
+// translation does not use are ignored.
+type Catalogue interface {
+	// ErrorMessage returns the message of a ParserError with the given
+	// code, formatted with the arguments documented for each
+	// ErrorCode in DefaultCatalogue.
+	ErrorMessage(code ErrorCode, args ...any) string
+
+	// Text returns the help or usage text for the given key, which is
+	// the English text itself, formatted with the given arguments, if
+	// any. The usage text of flag and command configs is looked up in
+	// the same way.
+	Text(key string, args ...any) string
+}
+
+// The keys of the help and usage text written by WriteUsage.
+const (
+	// TextUsage is the first line of usage, given the command path
+	// followed by its flags and positional arguments.
+	TextUsage = "usage: %[1]s"
+
+	TextCommands         = "commands:"
+	TextFlags            = "flags:"
+	TextFlagsPlaceholder = "[flags]"
+
+	// TextCommandPlaceholder stands for the sub-command in the first
+	// line of usage.
+	TextCommandPlaceholder = "<command>"
+
+	// TextValueName and TextArgName are the placeholders for unnamed
+	// flag values and positional arguments.
+	TextValueName = "value"
+	TextArgName   = "arg"
+
+	// TextOneValue and TextValues describe the exact number of values
+	// expected by a flag, and TextAtLeastOneValue and
+	// TextAtLeastValues the minimum, given the number.
+	TextOneValue        = "1 value"
+	TextValues          = "%[1]d values"
+	TextAtLeastOneValue = "at least 1 value"
+	TextAtLeastValues   = "at least %[1]d values"
+
+	// TextOnePositional and TextPositionals describe the exact number
+	// of positional arguments expected by a command, and
+	// TextAtLeastOnePositional and TextAtLeastPositionals the minimum,
+	// given the number.
+	TextOnePositional        = "1 positional argument"
+	TextPositionals          = "%[1]d positional arguments"
+	TextAtLeastOnePositional = "at least 1 positional argument"
+	TextAtLeastPositionals   = "at least %[1]d positional arguments"
+)
+
+// DefaultCatalogue is the English Catalogue used when a ParserConfig
+// does not specify one, and to which a MapCatalogue falls back.
+var DefaultCatalogue Catalogue = MapCatalogue{}
+
+var defaultErrorMessages = map[ErrorCode]string{
+	// args: flag name
+	ErrUnknownFlag: "unknown flag %[1]q",
+	// args: argument, comma-separated valid commands
+	ErrUnknownCommand: "unknown command %[1]q (valid commands: %[2]s)",
+	// args: argument
+	ErrUnexpectedArgument: "unexpected argument %[1]q",
+	// args: flag name, minimum, given, expected number as by
+	// TextValues and the like
+	ErrMissingValue: "flag %[1]q expects %[4]s, got %[3]d",
+	// args: command name, minimum, given, expected number as by
+	// TextPositionals and the like
+	ErrMissingPositional: "command %[1]q expects %[4]s, got %[3]d",
+	// args: none
+	ErrBareAssignment: "invalid bare assignment",
+	// args: pair, flag name
+	ErrInvalidKeyValue: "invalid key=value pair %[1]q for flag %[2]q",
+	// args: flag name
+	ErrRepeatedFlag: "flag %[1]q given more than once",
+	// args: "flag" or "command", name, handler error
+	ErrHandler: "%[1]s %[2]q handler: %[3]v",
+	// args: command path
+	ErrNoAction: "command %[1]q has no action",
+	// args: "before", "action" or "after", command path, error
+	ErrAction: "command %[2]q %[1]s: %[3]v",
+}
+
+// MapCatalogue is a Catalogue of translated message formats, falling
+// back to the English text for those not given.
+type MapCatalogue struct {
+	Errors map[ErrorCode]string
+	Texts  map[string]string
+}
+
+func (c MapCatalogue) ErrorMessage(code ErrorCode, args ...any) string {
+	format, ok := c.Errors[code]
+	if !ok {
+		format, ok = defaultErrorMessages[code]
+	}
+
+	if !ok {
+		return code.Error()
+	}
+
+	return sprintf(format, args...)
+}
+
+func (c MapCatalogue) Text(key string, args ...any) string {
+	format, ok := c.Texts[key]
+	if !ok {
+		format = key
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+
+	return sprintf(format, args...)
+}
+
+// sprintf is like fmt.Sprintf, except that any arguments left unused
+// by a format without explicit argument indexes are dropped instead
+// of being reported as %!(EXTRA ...), which fmt already does for
+// formats with them.
+func sprintf(format string, args ...any) string {
+	if !strings.Contains(format, "%[") {
+		args = args[:min(len(args), countArgs(format))]
+	}
+
+	return fmt.Sprintf(format, args...)
+}
+
+// countArgs returns the number of arguments used by a format without
+// explicit argument indexes, which is one for each verb other than %%
+// and for each * width or precision.
+func countArgs(format string) int {
+	n := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		for i++; i < len(format); i++ {
+			if format[i] == '*' {
+				n++
+				continue
+			}
+
+			if strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+				continue
+			}
+
+			if format[i] != '%' {
+				n++
+			}
+
+			break
+		}
+	}
+
+	return n
+}
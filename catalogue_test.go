@@ -0,0 +1,115 @@
+package argh_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+func TestCatalogue(t *testing.T) {
+	pCfg := argh.NewParserConfig()
+	pCfg.Strict = true
+	pCfg.Prog.SetFlagConfig("n", &argh.FlagConfig{NValue: 2})
+	pCfg.Prog.SetFlagConfig("D", &argh.FlagConfig{KeyValue: true})
+	pCfg.Catalogue = argh.MapCatalogue{
+		Errors: map[argh.ErrorCode]string{
+			argh.ErrUnknownFlag:  "option inconnue %[1]q",
+			argh.ErrMissingValue: "%[3]d valeur(s) sur %[2]d pour l'option %[1]q",
+
+			argh.ErrUnexpectedArgument: "argument inattendu",
+			argh.ErrInvalidKeyValue:    "paire invalide %q",
+		},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		expErr string
+	}{
+		{
+			name:   "translated",
+			args:   []string{"prog", "--nope"},
+			expErr: "11:option inconnue \"nope\"",
+		},
+		{
+			name:   "reordered arguments",
+			args:   []string{"prog", "-n", "1"},
+			expErr: "7:1 valeur(s) sur 2 pour l'option \"n\"",
+		},
+		{
+			name:   "omitted arguments",
+			args:   []string{"prog", "a"},
+			expErr: "6:argument inattendu",
+		},
+		{
+			name:   "omitted trailing arguments",
+			args:   []string{"prog", "-D", "x"},
+			expErr: "10:paire invalide \"x\"",
+		},
+		{
+			name:   "english fallback",
+			args:   []string{"prog", "="},
+			expErr: "6:invalid bare assignment",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			_, err := argh.ParseArgs(tc.args, pCfg)
+			r.EqualError(err, tc.expErr)
+		})
+	}
+}
+
+func TestDefaultCatalogue(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("unknown flag \"x\"", argh.DefaultCatalogue.ErrorMessage(argh.ErrUnknownFlag, "x"))
+	r.Equal("ErrorCode(42)", argh.DefaultCatalogue.ErrorMessage(argh.ErrorCode(42)))
+	r.Equal("usage: prog", argh.DefaultCatalogue.Text(argh.TextUsage, "prog"))
+	r.Equal("100% untranslated", argh.DefaultCatalogue.Text("100% untranslated"))
+}
+
+func TestCatalogueMissingCounts(t *testing.T) {
+	pCfg := argh.NewParserConfig()
+	pCfg.Prog.SetFlagConfig("o", &argh.FlagConfig{NValue: 1})
+	pCfg.Prog.SetFlagConfig("n", &argh.FlagConfig{NValue: 2})
+	pCfg.Prog.SetFlagConfig("tags", &argh.FlagConfig{NValue: argh.OneOrMoreValue})
+	pCfg.Prog.SetCommandConfig("rm", &argh.CommandConfig{NValue: argh.OneOrMoreValue})
+	pCfg.Prog.SetCommandConfig("mv", &argh.CommandConfig{NValue: 2})
+	pCfg.Prog.SetCommandConfig("cat", &argh.CommandConfig{NValue: 1})
+
+	for _, tc := range []struct {
+		args   []string
+		expErr string
+	}{
+		{
+			args:   []string{"prog", "-o"},
+			expErr: "7:flag \"o\" expects 1 value, got 0",
+		},
+		{
+			args:   []string{"prog", "-n", "a"},
+			expErr: "7:flag \"n\" expects 2 values, got 1",
+		},
+		{
+			args:   []string{"prog", "--tags"},
+			expErr: "11:flag \"tags\" expects at least 1 value, got 0",
+		},
+		{
+			args:   []string{"prog", "rm"},
+			expErr: "7:command \"rm\" expects at least 1 positional argument, got 0",
+		},
+		{
+			args:   []string{"prog", "mv", "a"},
+			expErr: "7:command \"mv\" expects 2 positional arguments, got 1",
+		},
+		{
+			args:   []string{"prog", "cat"},
+			expErr: "8:command \"cat\" expects 1 positional argument, got 0",
+		},
+	} {
+		_, err := argh.ParseArgs(tc.args, pCfg)
+		require.EqualError(t, err, tc.expErr)
+	}
+}
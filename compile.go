@@ -0,0 +1,232 @@
+package argh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompiledParser is an immutable, validated copy of a ParserConfig
+// that is safe for concurrent use by multiple goroutines, as long as
+// any configured handlers are as well.
+type CompiledParser struct {
+	cfg *ParserConfig
+}
+
+// Compile validates the given ParserConfig and returns a
+// CompiledParser holding a deep copy of it, with the flags that may
+// be given to each command indexed ahead of time. Changes made to the
+// given ParserConfig after compiling have no effect on the
+// CompiledParser.
+func Compile(pCfg *ParserConfig) (*CompiledParser, error) {
+	if pCfg == nil {
+		return nil, fmt.Errorf("nil parser config: %w", Err)
+	}
+
+	if pCfg.Prog == nil {
+		return nil, fmt.Errorf("nil program command config: %w", Err)
+	}
+
+	sCfg := POSIXyScannerConfig
+	if pCfg.ScannerConfig != nil {
+		sCfg = pCfg.ScannerConfig
+	}
+
+	if err := validateScannerConfig(sCfg); err != nil {
+		return nil, err
+	}
+
+	c := &compiler{
+		sCfg:  sCfg,
+		flags: map[*Flags]*Flags{},
+	}
+
+	prog, err := c.compileCommand(pCfg.Prog, []string{"<prog>"})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := *pCfg
+	scannerCfg := *sCfg
+
+	cfg.Prog = prog
+	cfg.ScannerConfig = &scannerCfg
+
+	return &CompiledParser{cfg: &cfg}, nil
+}
+
+// Parse parses the given args in the same way as ParseArgs.
+func (cp *CompiledParser) Parse(args []string) (*ParseTree, error) {
+	return ParseArgs(args, cp.cfg)
+}
+
+func validateScannerConfig(sCfg *ScannerConfig) error {
+	runes := map[rune]string{}
+
+	for _, r := range []struct {
+		name string
+		ch   rune
+	}{
+		{"assignment operator", sCfg.AssignmentOperator},
+		{"flag prefix", sCfg.FlagPrefix},
+		{"multi-value delimiter", sCfg.MultiValueDelim},
+	} {
+		if r.ch == 0 {
+			return fmt.Errorf("empty %[1]s: %[2]w", r.name, Err)
+		}
+
+		if other, ok := runes[r.ch]; ok {
+			return fmt.Errorf("%[1]s %[2]q is also the %[3]s: %[4]w", r.name, r.ch, other, Err)
+		}
+
+		runes[r.ch] = r.name
+	}
+
+	return nil
+}
+
+// compiler holds the state of a single call to Compile, mapping each
+// Flags to its copy so that Parent relationships are preserved.
+type compiler struct {
+	sCfg  *ScannerConfig
+	flags map[*Flags]*Flags
+}
+
+func (c *compiler) compileCommand(cCfg *CommandConfig, path []string) (*CommandConfig, error) {
+	cmdPath := strings.Join(path, " ")
+
+	if err := validateArity(cCfg.NValue, cCfg.Arity); err != nil {
+		return nil, fmt.Errorf("command %[1]q: %[2]w", cmdPath, err)
+	}
+
+	ret := *cCfg
+	ret.ValueNames = append([]string{}, cCfg.ValueNames...)
+	ret.Positionals = append([]PositionalConfig{}, cCfg.Positionals...)
+
+	if cCfg.Arity != nil {
+		arity := *cCfg.Arity
+		ret.Arity = &arity
+	}
+
+	flags, err := c.compileFlags(cCfg.Flags, cmdPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.Flags = flags
+	ret.Commands = &Commands{Map: map[string]CommandConfig{}}
+
+	if cCfg.Commands != nil {
+		for name, sCfg := range cCfg.Commands.Map {
+			if name == "" {
+				return nil, fmt.Errorf("command %[1]q: empty sub-command name: %[2]w", cmdPath, Err)
+			}
+
+			sCfg := sCfg
+
+			compiled, err := c.compileCommand(&sCfg, append(append([]string{}, path...), name))
+			if err != nil {
+				return nil, err
+			}
+
+			ret.Commands.Map[name] = *compiled
+		}
+	}
+
+	return &ret, nil
+}
+
+// compileFlags returns a copy of the given Flags, along with copies
+// of its parents, with the index of flags that may be given built.
+func (c *compiler) compileFlags(fl *Flags, cmdPath string) (*Flags, error) {
+	if fl == nil {
+		fl = &Flags{}
+	}
+
+	if compiled, ok := c.flags[fl]; ok {
+		return compiled, nil
+	}
+
+	ret := &Flags{
+		Map:                map[string]FlagConfig{},
+		Automatic:          fl.Automatic,
+		PassthroughUnknown: fl.PassthroughUnknown,
+		index:              map[string]indexedFlag{},
+	}
+
+	c.flags[fl] = ret
+
+	if fl.Parent != nil {
+		parent, err := c.compileFlags(fl.Parent, cmdPath)
+		if err != nil {
+			return nil, err
+		}
+
+		ret.Parent = parent
+	}
+
+	for name, flCfg := range fl.Map {
+		if err := c.validateFlag(name, flCfg); err != nil {
+			return nil, fmt.Errorf("command %[1]q: %[2]w", cmdPath, err)
+		}
+
+		flCfg.ValueNames = append([]string{}, flCfg.ValueNames...)
+
+		if flCfg.Arity != nil {
+			arity := *flCfg.Arity
+			flCfg.Arity = &arity
+		}
+
+		ret.Map[name] = flCfg
+		ret.index[name] = indexedFlag{flCfg: flCfg, owner: ret}
+	}
+
+	if !ret.Automatic && ret.Parent != nil {
+		for name, entry := range ret.Parent.index {
+			if _, ok := ret.index[name]; ok || !entry.flCfg.Persist {
+				continue
+			}
+
+			ret.index[name] = entry
+		}
+	}
+
+	for ch := '0'; ch <= '9'; ch++ {
+		if _, ok := ret.index[string(ch)]; ok {
+			ret.digitShortFlags = true
+		}
+	}
+
+	return ret, nil
+}
+
+func (c *compiler) validateFlag(name string, flCfg FlagConfig) error {
+	if name == "" {
+		return fmt.Errorf("empty flag name: %[1]w", Err)
+	}
+
+	if strings.ContainsRune(name, c.sCfg.AssignmentOperator) || c.sCfg.IsFlagPrefix([]rune(name)[0]) {
+		return fmt.Errorf("flag %[1]q: invalid flag name: %[2]w", name, Err)
+	}
+
+	if err := validateArity(flCfg.NValue, flCfg.Arity); err != nil {
+		return fmt.Errorf("flag %[1]q: %[2]w", name, err)
+	}
+
+	return nil
+}
+
+func validateArity(nv NValue, arity *Arity) error {
+	if nv < OneOrMoreValue {
+		return fmt.Errorf("invalid NValue %[1]v: %[2]w", nv, Err)
+	}
+
+	if arity == nil {
+		return nil
+	}
+
+	if arity.Min < 0 || (arity.Max >= 0 && arity.Max < arity.Min) {
+		return fmt.Errorf("invalid arity %[1]d..%[2]d: %[3]w", arity.Min, arity.Max, Err)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package argh_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+func TestCompile(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		cfg    *argh.ParserConfig
+		expErr string
+	}{
+		{
+			name:   "nil config",
+			expErr: "nil parser config",
+		},
+		{
+			name:   "nil program",
+			cfg:    &argh.ParserConfig{},
+			expErr: "nil program command config",
+		},
+		{
+			name: "conflicting scanner runes",
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{},
+				ScannerConfig: &argh.ScannerConfig{
+					AssignmentOperator: '=',
+					FlagPrefix:         '-',
+					MultiValueDelim:    '=',
+				},
+			},
+			expErr: "multi-value delimiter '=' is also the assignment operator",
+		},
+		{
+			name: "invalid flag name",
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{"a=b": {}},
+					},
+				},
+			},
+			expErr: "command \"<prog>\": flag \"a=b\": invalid flag name",
+		},
+		{
+			name: "invalid arity",
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Commands: &argh.Commands{
+						Map: map[string]argh.CommandConfig{
+							"sub": {
+								Flags: &argh.Flags{
+									Map: map[string]argh.FlagConfig{
+										"n": {Arity: &argh.Arity{Min: 3, Max: 1}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expErr: "command \"<prog> sub\": flag \"n\": invalid arity 3..1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			cp, err := argh.Compile(tc.cfg)
+			r.Nil(cp)
+			r.ErrorIs(err, argh.Err)
+			r.ErrorContains(err, tc.expErr)
+		})
+	}
+}
+
+func TestCompiledParser(t *testing.T) {
+	r := require.New(t)
+
+	pCfg := argh.NewParserConfig()
+	pCfg.NegativeNumbers = argh.NegativeNumbersAsValues
+	pCfg.Prog.SetFlagConfig("v", &argh.FlagConfig{Persist: true, Repeat: argh.RepeatCount})
+	pCfg.Prog.SetFlagConfig("color", &argh.FlagConfig{Negatable: true, Persist: true})
+
+	sub := &argh.CommandConfig{NValue: 1}
+	sub.SetFlagConfig("n", &argh.FlagConfig{NValue: 1})
+
+	pCfg.Prog.SetCommandConfig("sub", sub)
+
+	cp, err := argh.Compile(pCfg)
+	r.NoError(err)
+
+	// NOTE: changes after compiling must not affect the compiled
+	// parser.
+	pCfg.Prog.SetFlagConfig("late", &argh.FlagConfig{})
+
+	_, err = cp.Parse([]string{"prog", "--late"})
+	r.ErrorContains(err, "unknown flag \"late\"")
+
+	exp, err := argh.ParseArgs([]string{"prog", "-vv", "sub", "--no-color", "-n", "-1", "-v", "x"}, pCfg)
+	r.NoError(err)
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 32)
+
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			pt, err := cp.Parse([]string{"prog", "-vv", "sub", "--no-color", "-n", "-1", "-v", "x"})
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			prog := pt.Nodes[0].(*argh.Command)
+			if prog.Merged["v"].Count != 3 {
+				errs <- fmt.Errorf("unexpected count %d", prog.Merged["v"].Count)
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		r.NoError(err)
+	}
+
+	pt, err := cp.Parse([]string{"prog", "-vv", "sub", "--no-color", "-n", "-1", "-v", "x"})
+	r.NoError(err)
+	r.Equal(argh.ToAST(exp.Nodes), argh.ToAST(pt.Nodes))
+}
+
+func TestCompiledParserConfiguredNegatedSpelling(t *testing.T) {
+	r := require.New(t)
+
+	pCfg := argh.NewParserConfig()
+	pCfg.Prog.SetFlagConfig("color", &argh.FlagConfig{Negatable: true})
+	pCfg.Prog.SetFlagConfig("no-color", &argh.FlagConfig{NValue: 1})
+
+	cp, err := argh.Compile(pCfg)
+	r.NoError(err)
+
+	args := []string{"prog", "--color", "--no-color", "never"}
+
+	exp, err := argh.ParseArgs(args, pCfg)
+	r.NoError(err)
+
+	pt, err := cp.Parse(args)
+	r.NoError(err)
+	r.Equal(argh.ToAST(exp.Nodes), argh.ToAST(pt.Nodes))
+
+	prog := pt.Nodes[0].(*argh.Command)
+	r.Equal(
+		[]argh.Node{
+			&argh.Flag{
+				Name:      "color",
+				Values:    map[string]string{"0": "true"},
+				ValueList: []argh.Value{{Name: "0", Literal: "true", Pos: argh.Position{Column: 12}, Source: argh.ValueFromFlag}},
+			},
+			&argh.Flag{
+				Name:      "no-color",
+				Values:    map[string]string{"0": "never"},
+				Nodes:     []argh.Node{&argh.Ident{Literal: "never"}},
+				ValueList: []argh.Value{{Name: "0", Literal: "never", Pos: argh.Position{Column: 29}}},
+			},
+		},
+		argh.ToAST(prog.Nodes),
+	)
+}
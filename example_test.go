@@ -1,6 +1,7 @@
 package argh_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -156,6 +157,26 @@ func ExampleParserConfig() {
 	//           {
 	//             "Literal": "the"
 	//           }
+	//         ],
+	//         "ValueList": [
+	//           {
+	//             "Name": "0",
+	//             "Literal": "from",
+	//             "Pos": {
+	//               "Column": 13
+	//             },
+	//             "Source": 1,
+	//             "Group": 0
+	//           },
+	//           {
+	//             "Name": "1",
+	//             "Literal": "the",
+	//             "Pos": {
+	//               "Column": 17
+	//             },
+	//             "Source": 0,
+	//             "Group": 1
+	//           }
 	//         ]
 	//       },
 	//       {},
@@ -186,6 +207,17 @@ func ExampleParserConfig() {
 	//               {
 	//                 "Literal": "hurlish"
 	//               }
+	//             ],
+	//             "ValueList": [
+	//               {
+	//                 "Name": "feels",
+	//                 "Literal": "hurlish",
+	//                 "Pos": {
+	//                   "Column": 44
+	//                 },
+	//                 "Source": 1,
+	//                 "Group": 0
+	//               }
 	//             ]
 	//           },
 	//           {},
@@ -202,8 +234,48 @@ func ExampleParserConfig() {
 	//             "Values": null,
 	//             "Nodes": null
 	//           }
+	//         ],
+	//         "ValueList": [
+	//           {
+	//             "Name": "pilot",
+	//             "Literal": "marge",
+	//             "Pos": {
+	//               "Column": 33
+	//             },
+	//             "Source": 0,
+	//             "Group": 0
+	//           },
+	//           {
+	//             "Name": "navigator",
+	//             "Literal": "patty",
+	//             "Pos": {
+	//               "Column": 50
+	//             },
+	//             "Source": 0,
+	//             "Group": 1
+	//           },
+	//           {
+	//             "Name": "comms",
+	//             "Literal": "selma",
+	//             "Pos": {
+	//               "Column": 56
+	//             },
+	//             "Source": 0,
+	//             "Group": 2
+	//           }
 	//         ]
 	//       }
+	//     ],
+	//     "ValueList": [
+	//       {
+	//         "Name": "val",
+	//         "Literal": "ether",
+	//         "Pos": {
+	//           "Column": 23
+	//         },
+	//         "Source": 0,
+	//         "Group": 0
+	//       }
 	//     ]
 	//   },
 	//   "sub": {
@@ -229,6 +301,17 @@ func ExampleParserConfig() {
 	//           {
 	//             "Literal": "hurlish"
 	//           }
+	//         ],
+	//         "ValueList": [
+	//           {
+	//             "Name": "feels",
+	//             "Literal": "hurlish",
+	//             "Pos": {
+	//               "Column": 44
+	//             },
+	//             "Source": 1,
+	//             "Group": 0
+	//           }
 	//         ]
 	//       },
 	//       {},
@@ -245,6 +328,35 @@ func ExampleParserConfig() {
 	//         "Values": null,
 	//         "Nodes": null
 	//       }
+	//     ],
+	//     "ValueList": [
+	//       {
+	//         "Name": "pilot",
+	//         "Literal": "marge",
+	//         "Pos": {
+	//           "Column": 33
+	//         },
+	//         "Source": 0,
+	//         "Group": 0
+	//       },
+	//       {
+	//         "Name": "navigator",
+	//         "Literal": "patty",
+	//         "Pos": {
+	//           "Column": 50
+	//         },
+	//         "Source": 0,
+	//         "Group": 1
+	//       },
+	//       {
+	//         "Name": "comms",
+	//         "Literal": "selma",
+	//         "Pos": {
+	//           "Column": 56
+	//         },
+	//         "Source": 0,
+	//         "Group": 2
+	//       }
 	//     ]
 	//   }
 	// }
@@ -264,6 +376,26 @@ func ExampleParserConfig() {
 	//       {
 	//         "Literal": "the"
 	//       }
+	//     ],
+	//     "ValueList": [
+	//       {
+	//         "Name": "0",
+	//         "Literal": "from",
+	//         "Pos": {
+	//           "Column": 13
+	//         },
+	//         "Source": 1,
+	//         "Group": 0
+	//       },
+	//       {
+	//         "Name": "1",
+	//         "Literal": "the",
+	//         "Pos": {
+	//           "Column": 17
+	//         },
+	//         "Source": 0,
+	//         "Group": 1
+	//       }
 	//     ]
 	//   },
 	//   "b": {
@@ -281,7 +413,53 @@ func ExampleParserConfig() {
 	//       {
 	//         "Literal": "hurlish"
 	//       }
+	//     ],
+	//     "ValueList": [
+	//       {
+	//         "Name": "feels",
+	//         "Literal": "hurlish",
+	//         "Pos": {
+	//           "Column": 44
+	//         },
+	//         "Source": 1,
+	//         "Group": 0
+	//       }
 	//     ]
 	//   }
 	// }
 }
+
+func ExampleRun() {
+	pCfg := argh.NewParserConfig()
+	pCfg.Prog.SetFlagConfig("loud", &argh.FlagConfig{Persist: true})
+	pCfg.Prog.Before = func(_ context.Context, inv *argh.Invocation) error {
+		fmt.Printf("before %[1]q\n", inv.Path)
+		return nil
+	}
+
+	pCfg.Prog.SetCommandConfig("greet", &argh.CommandConfig{
+		NValue:     1,
+		ValueNames: []string{"name"},
+		Action: func(_ context.Context, inv *argh.Invocation) error {
+			greeting := "hello"
+			if _, ok := inv.Command().Bool("loud"); ok {
+				greeting = "HELLO"
+			}
+
+			fmt.Printf("%[1]s, %[2]s\n", greeting, inv.Command().Values["name"])
+			return nil
+		},
+	})
+
+	res, err := argh.Run(context.Background(), []string{"prog", "greet", "--loud", "world"}, pCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("action called: %[1]v\n", res.ActionCalled)
+
+	// Output:
+	// before ["prog" "greet"]
+	// HELLO, world
+	// action called: true
+}
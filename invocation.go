@@ -0,0 +1,196 @@
+package argh
+
+// Invocation is the command invoked by a command line, along with the
+// commands on the path to it from the program and what was given to
+// each of them.
+type Invocation struct {
+	// Path is the names of the commands from the program to the
+	// invoked command, e.g. ["prog", "sub"].
+	Path []string
+
+	// Commands and Configs are the parsed nodes and the configs of
+	// the commands along Path.
+	Commands []*Command
+	Configs  []*CommandConfig
+
+	// Flags are the flags given to every command along Path in the
+	// order given, for which see EffectiveFlags.
+	Flags []InvocationFlag
+
+	// Positionals are the positional values of the invoked command.
+	Positionals []Value
+
+	// Passthrough holds the arguments passed through verbatim in the
+	// order given, such as those following a StopFlag and unknown
+	// flags with Flags.PassthroughUnknown.
+	Passthrough []string
+}
+
+// InvocationFlag is a flag given in an Invocation.
+type InvocationFlag struct {
+	Flag   *Flag
+	Config FlagConfig
+
+	// Level is the index in Path of the command the flag was given
+	// to, and Owner that of the command configuring it, which is less
+	// than Level for persistent flags of ancestors.
+	Level int
+	Owner int
+}
+
+// Command returns the node of the invoked command.
+func (inv *Invocation) Command() *Command {
+	return inv.Commands[len(inv.Commands)-1]
+}
+
+// Config returns the config of the invoked command.
+func (inv *Invocation) Config() *CommandConfig {
+	return inv.Configs[len(inv.Configs)-1]
+}
+
+// EffectiveFlags returns the flags in effect for the command at the
+// given level of Path in the order given, which are its own flags
+// along with the persistent flags of its ancestors, wherever along
+// Path they were given.
+func (inv *Invocation) EffectiveFlags(level int) []InvocationFlag {
+	ret := []InvocationFlag{}
+
+	for _, invFlag := range inv.Flags {
+		if invFlag.Owner == level || (invFlag.Owner < level && invFlag.Config.Persist) {
+			ret = append(ret, invFlag)
+		}
+	}
+
+	return ret
+}
+
+// Flag returns the last occurrence of the named flag in effect for
+// the invoked command.
+func (inv *Invocation) Flag(name string) (InvocationFlag, bool) {
+	flags := inv.EffectiveFlags(len(inv.Path) - 1)
+
+	for i := len(flags) - 1; i >= 0; i-- {
+		if flags[i].Flag.Name == name {
+			return flags[i], true
+		}
+	}
+
+	return InvocationFlag{}, false
+}
+
+// Bool returns the boolean state of the named flag in effect for the
+// invoked command in the same way as Command.Bool.
+func (inv *Invocation) Bool(name string) (bool, bool) {
+	invFlag, ok := inv.Flag(name)
+	if !ok {
+		return false, false
+	}
+
+	return !invFlag.Flag.Negated, true
+}
+
+// Positional returns the named positional value of the invoked
+// command.
+func (inv *Invocation) Positional(name string) (string, bool) {
+	for _, value := range inv.Positionals {
+		if value.Name == name {
+			return value.Literal, true
+		}
+	}
+
+	return "", false
+}
+
+// resolveInvocation follows the sub-commands parsed from the program
+// command down to the invoked command, collecting what was given to
+// each. The configs recorded in the ParseTree for commands and flags
+// configured via a Scope take precedence over those of the
+// ParserConfig.
+func resolveInvocation(pt *ParseTree, pCfg *ParserConfig) *Invocation {
+	inv := &Invocation{}
+
+	if len(pt.Nodes) == 0 {
+		return inv
+	}
+
+	node, _ := pt.Nodes[0].(*Command)
+
+	cCfg := pCfg.Prog
+	if recorded, ok := pt.commandConfigs[node]; ok {
+		cCfg = recorded
+	}
+
+	for node != nil {
+		level := len(inv.Path)
+
+		inv.Path = append(inv.Path, node.Name)
+		inv.Commands = append(inv.Commands, node)
+		inv.Configs = append(inv.Configs, cCfg)
+
+		var next *Command
+
+		var visit func(nodes []Node)
+
+		visit = func(nodes []Node) {
+			for _, child := range nodes {
+				switch v := child.(type) {
+				case *CompoundShortFlag:
+					visit(v.Nodes)
+				case *Flag:
+					inv.Flags = append(inv.Flags, inv.resolveFlag(pt, v, level))
+				case *UnknownFlag:
+					inv.Passthrough = append(inv.Passthrough, v.Literal)
+				case *PassthroughArgs:
+					for _, arg := range v.Nodes {
+						if ident, ok := arg.(*Ident); ok {
+							inv.Passthrough = append(inv.Passthrough, ident.Literal)
+						}
+					}
+				case *Command:
+					if next != nil {
+						continue
+					}
+
+					if recorded, ok := pt.commandConfigs[v]; ok {
+						next, cCfg = v, recorded
+					} else if subCfg, ok := cCfg.GetCommandConfig(v.Name); ok {
+						next, cCfg = v, &subCfg
+					}
+				}
+			}
+		}
+
+		visit(node.Nodes)
+
+		if next == nil {
+			inv.Positionals = node.ValueList
+		}
+
+		node = next
+	}
+
+	return inv
+}
+
+func (inv *Invocation) resolveFlag(pt *ParseTree, node *Flag, level int) InvocationFlag {
+	recorded, ok := pt.flagConfigs[node]
+	if !ok {
+		recorded.flCfg, recorded.owner, _ = inv.Configs[level].Flags.lookup(node.Name)
+	}
+
+	invFlag := InvocationFlag{
+		Flag:   node,
+		Config: recorded.flCfg,
+		Level:  level,
+		Owner:  level,
+	}
+
+	for i := level; i >= 0; i-- {
+		if inv.Configs[i].Flags == recorded.owner {
+			invFlag.Owner = i
+			break
+		}
+	}
+
+	return invFlag
+}
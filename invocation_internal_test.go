@@ -0,0 +1,11 @@
+package argh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveInvocationEmptyTree(t *testing.T) {
+	require.Equal(t, &Invocation{}, resolveInvocation(&ParseTree{}, NewParserConfig()))
+}
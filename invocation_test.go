@@ -0,0 +1,145 @@
+package argh_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+func TestInvocation(t *testing.T) {
+	r := require.New(t)
+
+	var inv *argh.Invocation
+
+	pCfg := argh.NewParserConfig()
+	pCfg.Prog.SetFlagConfig("v", &argh.FlagConfig{Persist: true, Repeat: argh.RepeatCount})
+	pCfg.Prog.SetFlagConfig("b", &argh.FlagConfig{Persist: true, Negatable: true})
+	pCfg.Prog.SetFlagConfig("local", &argh.FlagConfig{})
+
+	sub := &argh.CommandConfig{
+		Positionals: []argh.PositionalConfig{{Name: "src"}, {Name: "dst", Optional: true}},
+		Action: func(_ context.Context, i *argh.Invocation) error {
+			inv = i
+			return nil
+		},
+	}
+	sub.SetFlagConfig("n", &argh.FlagConfig{NValue: 1})
+
+	pCfg.Prog.SetCommandConfig("sub", sub)
+
+	_, err := argh.Run(
+		context.Background(),
+		[]string{"prog", "-v", "--local", "sub", "-vb", "x", "-n", "1", "--no-b", "--", "a", "-z"},
+		pCfg,
+	)
+	r.NoError(err)
+	r.NotNil(inv)
+
+	r.Equal([]string{"prog", "sub"}, inv.Path)
+	r.Len(inv.Commands, 2)
+	r.Len(inv.Configs, 2)
+	r.Equal("sub", inv.Command().Name)
+	r.NotNil(inv.Config().Action)
+
+	type given struct {
+		name         string
+		level, owner int
+	}
+
+	summarize := func(flags []argh.InvocationFlag) []given {
+		ret := []given{}
+
+		for _, invFlag := range flags {
+			ret = append(ret, given{name: invFlag.Flag.Name, level: invFlag.Level, owner: invFlag.Owner})
+		}
+
+		return ret
+	}
+
+	r.Equal(
+		[]given{
+			{"v", 0, 0}, {"local", 0, 0},
+			{"v", 1, 0}, {"b", 1, 0}, {"n", 1, 1}, {"b", 1, 0},
+		},
+		summarize(inv.Flags),
+	)
+
+	r.Equal(
+		[]given{{"v", 0, 0}, {"local", 0, 0}, {"v", 1, 0}, {"b", 1, 0}, {"b", 1, 0}},
+		summarize(inv.EffectiveFlags(0)),
+	)
+
+	r.Equal(
+		[]given{{"v", 0, 0}, {"v", 1, 0}, {"b", 1, 0}, {"n", 1, 1}, {"b", 1, 0}},
+		summarize(inv.EffectiveFlags(1)),
+	)
+
+	n, ok := inv.Flag("n")
+	r.True(ok)
+	r.Equal(map[string]string{"0": "1"}, n.Flag.Values)
+	r.Equal(argh.NValue(1), n.Config.NValue)
+
+	_, ok = inv.Flag("local")
+	r.False(ok, "non-persistent flags of ancestors are not in effect")
+
+	b, ok := inv.Bool("b")
+	r.True(ok)
+	r.False(b)
+
+	src, ok := inv.Positional("src")
+	r.True(ok)
+	r.Equal("x", src)
+
+	_, ok = inv.Positional("dst")
+	r.False(ok)
+
+	r.Equal([]string{"a", "-z"}, inv.Passthrough)
+
+	cp, err := argh.Compile(pCfg)
+	r.NoError(err)
+
+	flags := inv.Flags
+
+	_, err = cp.Run(
+		context.Background(),
+		[]string{"prog", "-v", "--local", "sub", "-vb", "x", "-n", "1", "--no-b", "--", "a", "-z"},
+	)
+	r.NoError(err)
+	r.Equal(summarize(flags), summarize(inv.Flags))
+}
+
+func TestInvocationPassthroughUnknown(t *testing.T) {
+	r := require.New(t)
+
+	var inv *argh.Invocation
+
+	pCfg := argh.NewParserConfig()
+	pCfg.Prog.Flags.PassthroughUnknown = true
+
+	build := &argh.CommandConfig{
+		Flags: &argh.Flags{PassthroughUnknown: true},
+		Action: func(_ context.Context, i *argh.Invocation) error {
+			inv = i
+			return nil
+		},
+	}
+	build.SetFlagConfig("v", &argh.FlagConfig{})
+
+	pCfg.Prog.SetCommandConfig("build", build)
+
+	_, err := argh.Run(
+		context.Background(),
+		[]string{"prog", "--trace", "build", "-v", "--gc-flags=-N,-l", "-race", "-vx=1", "--", "./..."},
+		pCfg,
+	)
+	r.NoError(err)
+	r.NotNil(inv)
+
+	r.Len(inv.Flags, 2)
+	r.Equal("v", inv.Flags[0].Flag.Name)
+	r.Equal("v", inv.Flags[1].Flag.Name)
+
+	r.Equal([]string{"--trace", "--gc-flags=-N,-l", "-race", "-x=1", "./..."}, inv.Passthrough)
+}
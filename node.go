@@ -18,12 +18,28 @@ type PassthroughArgs struct {
 	Nodes []Node
 }
 
+// ListTerminator is the configured argument that ended the values
+// of a flag
+type ListTerminator struct {
+	Literal string
+}
+
+// UnknownFlag is a flag that is not configured, holding the
+// verbatim argument as given, e.g. "--gc-flags=-N,-l"
+type UnknownFlag struct {
+	Literal string
+}
+
 type CompoundShortFlag struct {
 	Nodes []Node
 }
 
 type MultiIdent struct {
 	Nodes []Node
+
+	// Delim is the multi-value delimiter of the flag when it differs
+	// from that of the ScannerConfig
+	Delim rune `json:",omitempty"`
 }
 
 // Command is a Node with a name, a slice of child Nodes, and
@@ -32,6 +48,34 @@ type Command struct {
 	Name   string
 	Values map[string]string
 	Nodes  []Node
+
+	// ValueList holds the positional arguments in Values in the
+	// order given
+	ValueList []Value `json:",omitempty"`
+
+	// Merged holds the folded occurrences of each flag configured
+	// in this command with a RepeatMode other than RepeatEach,
+	// including occurrences given to sub-commands of persistent
+	// flags, keyed by flag name
+	Merged map[string]MergedFlag `json:",omitempty"`
+}
+
+// MergedFlag is the result of folding every occurrence of a flag
+// according to its RepeatMode
+type MergedFlag struct {
+	Name string
+
+	// Count is the number of occurrences, which for RepeatCount is
+	// reset to zero by the negated spelling of a Negatable flag
+	Count int
+
+	// Values are the values of all occurrences in order for
+	// RepeatAppend, and those of the last occurrence otherwise
+	Values []string
+
+	// Pairs are the key/value pairs of a KeyValue flag, folded in
+	// the same way as Values
+	Pairs []Pair `json:",omitempty"`
 }
 
 // Flag is a Node with a name, a slice of child Nodes, and
@@ -40,24 +84,86 @@ type Flag struct {
 	Name   string
 	Values map[string]string
 	Nodes  []Node
-}
 
-type CommandError struct {
-	Pos  Position
-	Node Node
-	Msg  string
+	// Negated is true when a Negatable flag was given with its
+	// negated spelling, e.g. "--no-color"
+	Negated bool `json:",omitempty"`
+
+	// ValueList holds the values in Values in the order given
+	ValueList []Value `json:",omitempty"`
+
+	// Pairs are the key/value pairs of a KeyValue flag in the order
+	// given
+	Pairs []Pair `json:",omitempty"`
 }
 
-func (e CommandError) Error() string {
-	return e.Msg
+// Value is a named value of a Flag or Command along with where and
+// how it was given
+type Value struct {
+	Name    string
+	Literal string
+
+	// Pos is the position of the last rune of the value
+	Pos Position
+
+	Source ValueSource
+
+	// Group is the index of the argument or multi-value list from
+	// which the value was taken, which is shared by every value in
+	// the same multi-value list
+	Group int
 }
 
-type FlagError struct {
-	Pos  Position
-	Node Node
-	Msg  string
+const (
+	// ValueFromArg is a value given as its own argument
+	ValueFromArg ValueSource = iota
+
+	// ValueFromAssign is a value joined to the flag via the
+	// assignment operator, e.g. "--color=always"
+	ValueFromAssign
+
+	// ValueFromAttached is a value directly attached to a short
+	// flag, e.g. "-ofile.txt"
+	ValueFromAttached
+
+	// ValueFromFlag is a value implied by the spelling of the flag,
+	// e.g. "false" for "--no-color"
+	ValueFromFlag
+)
+
+// ValueSource is how a Value was given
+type ValueSource int
+
+// Pair is a key/value pair given to a KeyValue flag
+type Pair struct {
+	Key   string
+	Value string
 }
 
-func (e FlagError) Error() string {
-	return e.Msg
+// Bool returns the boolean state of the named flag among the
+// command's direct child Nodes, including those within compound
+// short flag groups. A flag that is present is true unless it was
+// given with its negated spelling, and the last occurrence wins.
+// The second return value is false when the flag is absent.
+func (cmd *Command) Bool(name string) (bool, bool) {
+	value, found := false, false
+
+	var visit func(nodes []Node)
+
+	visit = func(nodes []Node) {
+		for _, node := range nodes {
+			switch v := node.(type) {
+			case *CompoundShortFlag:
+				visit(v.Nodes)
+			case *Flag:
+				if v.Name == name {
+					value, found = !v.Negated, true
+				}
+			}
+		}
+	}
+
+	visit(cmd.Nodes)
+
+	return value, found
 }
@@ -14,7 +14,7 @@ func TestCommandError(t *testing.T) {
 		Msg:  "unable to stop at this time",
 	}
 
-	require.Equal(t, "unable to stop at this time", fmt.Sprintf("%[1]v", err))
+	require.Equal(t, "42:unable to stop at this time", fmt.Sprintf("%[1]v", err))
 }
 
 func TestFlagError(t *testing.T) {
@@ -24,5 +24,40 @@ func TestFlagError(t *testing.T) {
 		Msg:  "am just not that into you",
 	}
 
-	require.Equal(t, "am just not that into you", fmt.Sprintf("%[1]v", err))
+	require.Equal(t, "42:am just not that into you", fmt.Sprintf("%[1]v", err))
+}
+
+func TestCommandBool(t *testing.T) {
+	r := require.New(t)
+
+	cmd := &Command{
+		Name: "pizzas",
+		Nodes: []Node{
+			&Flag{Name: "cheese", Negated: true},
+			&CompoundShortFlag{
+				Nodes: []Node{
+					&Flag{Name: "h"},
+					&Flag{Name: "o"},
+				},
+			},
+			&Flag{Name: "cheese"},
+			&Flag{Name: "sauce"},
+			&Flag{Name: "sauce", Negated: true},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		expValue bool
+		expFound bool
+	}{
+		{name: "cheese", expValue: true, expFound: true},
+		{name: "sauce", expValue: false, expFound: true},
+		{name: "h", expValue: true, expFound: true},
+		{name: "pineapple", expValue: false, expFound: false},
+	} {
+		value, found := cmd.Bool(tc.name)
+		r.Equal(tc.expValue, value, tc.name)
+		r.Equal(tc.expFound, found, tc.name)
+	}
 }
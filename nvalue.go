@@ -32,8 +32,6 @@ func (nv NValue) Required() bool {
 // of the NValue, which will always be false for negative integers
 // and will always be true for OneOrMoreValue or ZeroOrMoreValue.
 func (nv NValue) Contains(i int) bool {
-	tracef("NValue.Contains(%v)", i)
-
 	if i < int(ZeroValue) {
 		return false
 	}
@@ -44,3 +42,52 @@ func (nv NValue) Contains(i int) bool {
 
 	return int(nv) > i
 }
+
+// Arity returns the range of value counts represented by the NValue.
+func (nv NValue) Arity() Arity {
+	switch nv {
+	case OneOrMoreValue:
+		return Arity{Min: 1, Max: -1}
+	case ZeroOrMoreValue:
+		return Arity{Min: 0, Max: -1}
+	}
+
+	if nv < ZeroValue {
+		return Arity{}
+	}
+
+	return Arity{Min: int(nv), Max: int(nv)}
+}
+
+// Arity is an inclusive range of the number of values that may be
+// given, where a negative Max means there is no upper bound.
+type Arity struct {
+	Min int
+	Max int
+}
+
+// Required returns whether at least one value must be given.
+func (a Arity) Required() bool {
+	return a.Min >= 1
+}
+
+// Contains returns whether the given *index* is within the range of
+// the Arity, which will always be false for negative integers.
+func (a Arity) Contains(i int) bool {
+	if i < 0 {
+		return false
+	}
+
+	return a.Max < 0 || a.Max > i
+}
+
+// fits returns whether the given *number* of values is within the
+// range of the Arity.
+func (a Arity) fits(n int) bool {
+	return n >= a.Min && (a.Max < 0 || n <= a.Max)
+}
+
+// IsRange returns whether the number of values may vary.
+func (a Arity) IsRange() bool {
+	return a.Min != a.Max
+}
@@ -39,4 +39,29 @@ func TestNValue(t *testing.T) {
 		r.True(NValue(42).Contains(41))
 		r.False(NValue(42).Contains(-1))
 	})
+	t.Run("Arity", func(t *testing.T) {
+		r := require.New(t)
+		r.Equal(Arity{Min: 1, Max: -1}, OneOrMoreValue.Arity())
+		r.Equal(Arity{Min: 0, Max: -1}, ZeroOrMoreValue.Arity())
+		r.Equal(Arity{}, ZeroValue.Arity())
+		r.Equal(Arity{Min: 42, Max: 42}, NValue(42).Arity())
+	})
+}
+
+func TestArity(t *testing.T) {
+	r := require.New(t)
+
+	a := Arity{Min: 1, Max: 3}
+	r.True(a.Required())
+	r.True(a.IsRange())
+	r.False(a.Contains(-1))
+	r.True(a.Contains(0))
+	r.True(a.Contains(2))
+	r.False(a.Contains(3))
+
+	unbounded := Arity{Max: -1}
+	r.False(unbounded.Required())
+	r.True(unbounded.Contains(42))
+
+	r.False(Arity{Min: 2, Max: 2}.IsRange())
 }
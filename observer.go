@@ -0,0 +1,65 @@
+package argh
+
+// Observer receives the events of a parse in the order they occur,
+// as an alternative to the handlers of flag and command configs.
+type Observer interface {
+	Observe(Event)
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc func(Event)
+
+func (fn ObserverFunc) Observe(ev Event) {
+	fn(ev)
+}
+
+// EventKind is the kind of an Event.
+type EventKind int
+
+const (
+	// EventCommandEnter is observed as soon as the name of a command
+	// has been parsed, with the *Command as its Node, and
+	// EventCommandExit once the command and any sub-command have been
+	// parsed, with the completed *Command.
+	EventCommandEnter EventKind = iota + 1
+	EventCommandExit
+
+	// EventFlag is observed once a flag and its values have been
+	// parsed, with the *Flag as its Node, followed by an EventValue
+	// for each value in the order given.
+	EventFlag
+	EventValue
+
+	// EventPositional is observed for every positional argument of a
+	// command, with the *Command being parsed as its Node. The Name
+	// of the Value is empty when the command binds its Positionals
+	// once all have been given, or for arguments in excess of those
+	// expected.
+	EventPositional
+
+	// EventPassthrough is observed for every argument passed through
+	// verbatim, such as those following a StopFlag or an unknown flag
+	// with Flags.PassthroughUnknown.
+	EventPassthrough
+
+	// EventError is observed for every ParserError, including those
+	// that end parsing.
+	EventError
+)
+
+// Event is a single step of a parse, as observed by an Observer.
+type Event struct {
+	Kind EventKind
+
+	// Pos is the position of the end of the argument the event
+	// relates to.
+	Pos Position
+
+	// Name is the name of the command or flag, or that of the value
+	// for EventPositional.
+	Name string
+
+	Node  Node
+	Value Value
+	Err   *ParserError
+}
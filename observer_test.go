@@ -0,0 +1,96 @@
+package argh_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+func TestObserver(t *testing.T) {
+	r := require.New(t)
+
+	events := []string{}
+
+	pCfg := argh.NewParserConfig()
+	pCfg.Strict = true
+	pCfg.Observer = argh.ObserverFunc(func(ev argh.Event) {
+		desc := map[argh.EventKind]string{
+			argh.EventCommandEnter: "enter",
+			argh.EventCommandExit:  "exit",
+			argh.EventFlag:         "flag",
+			argh.EventValue:        "value",
+			argh.EventPositional:   "positional",
+			argh.EventPassthrough:  "passthrough",
+			argh.EventError:        "error",
+		}[ev.Kind]
+
+		switch ev.Kind {
+		case argh.EventValue, argh.EventPositional, argh.EventPassthrough:
+			desc += fmt.Sprintf(" %s %s=%q", ev.Name, ev.Value.Name, ev.Value.Literal)
+		case argh.EventError:
+			desc += fmt.Sprintf(" %v", ev.Err.Code)
+		default:
+			desc += " " + ev.Name
+		}
+
+		events = append(events, fmt.Sprintf("%d:%s", ev.Pos.Column, desc))
+	})
+
+	pCfg.Prog.SetFlagConfig("n", &argh.FlagConfig{NValue: 2, ValueNames: []string{"x", "y"}})
+	pCfg.Prog.SetFlagConfig("q", &argh.FlagConfig{})
+
+	cp := &argh.CommandConfig{NValue: 1, ValueNames: []string{"src"}}
+	cp.SetFlagConfig("color", &argh.FlagConfig{Negatable: true})
+
+	pCfg.Prog.SetCommandConfig("cp", cp)
+
+	_, err := argh.ParseArgs(
+		[]string{"prog", "-qn", "1", "2", "cp", "--no-color", "a", "b", "--", "c", "-d"},
+		pCfg,
+	)
+	r.ErrorIs(err, argh.ErrUnexpectedArgument)
+
+	r.Equal(
+		[]string{
+			`4:enter prog`,
+			`8:flag q`,
+			`8:flag n`,
+			`10:value n x="1"`,
+			`12:value n y="2"`,
+			`15:enter cp`,
+			`26:flag color`,
+			`26:value color 0="false"`,
+			`28:positional src src="a"`,
+			`30:positional  ="b"`,
+			`30:error unexpected argument`,
+			`35:passthrough  ="c"`,
+			`38:passthrough  ="-d"`,
+			`39:exit cp`,
+			`39:exit prog`,
+		},
+		events,
+	)
+}
+
+func TestObserverPassthroughUnknown(t *testing.T) {
+	r := require.New(t)
+
+	events := []argh.Event{}
+
+	pCfg := argh.NewParserConfig()
+	pCfg.Prog.Flags.PassthroughUnknown = true
+	pCfg.Observer = argh.ObserverFunc(func(ev argh.Event) {
+		if ev.Kind == argh.EventPassthrough {
+			events = append(events, ev)
+		}
+	})
+
+	_, err := argh.ParseArgs([]string{"prog", "--gc-flags=-N,-l"}, pCfg)
+	r.Nil(err)
+
+	r.Len(events, 1)
+	r.Equal("--gc-flags=-N,-l", events[0].Value.Literal)
+	r.IsType(&argh.UnknownFlag{}, events[0].Node)
+}
@@ -2,191 +2,547 @@ package argh
 
 import (
 	"fmt"
-	"io"
+	"log/slog"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
-type parser struct {
-	s *Scanner
+// Parser parses args according to a ParserConfig, reusing its
+// internal state across calls to Parse. A Parser is not safe for
+// concurrent use, for which see Compile.
+type Parser struct {
+	s Scanner
 
 	cfg *ParserConfig
 
 	errors ParserErrorList
 
+	merged map[*Flags]map[string]*MergedFlag
+
 	tok Token
 	lit string
 	pos Pos
 
 	buffered bool
+
+	log      *slog.Logger
+	eventsOn bool
+	traceOn  bool
+
+	// scopedFlags and scopedCommands hold the flags and sub-commands
+	// configured via a Scope during the current parse.
+	scopedFlags    map[*Flags]map[string]FlagConfig
+	scopedCommands map[*CommandConfig]map[string]CommandConfig
+
+	// scopedParents maps each Flags given a different Parent for the
+	// current parse to its copy, for which see reparentFlags.
+	scopedParents map[*Flags]*Flags
+
+	// path is the names of the commands being parsed, for errors.
+	path []string
+
+	flagArena  arena[Flag]
+	identArena arena[Ident]
+	nodeArena  arena[Node]
+	valueArena arena[Value]
+
+	// commandConfigs and flagConfigs are handed over to the
+	// ParseTree, for which see recordCommand and recordFlag.
+	commandConfigs map[*Command]*CommandConfig
+	flagConfigs    map[*Flag]indexedFlag
+
+	observer Observer
 }
 
 type ParseTree struct {
 	Nodes []Node `json:"nodes"`
+
+	// commandConfigs and flagConfigs hold the configs with which
+	// nodes were parsed once anything was configured via a Scope,
+	// which take precedence over the ParserConfig when resolving an
+	// Invocation.
+	commandConfigs map[*Command]*CommandConfig
+	flagConfigs    map[*Flag]indexedFlag
 }
 
 func ParseArgs(args []string, pCfg *ParserConfig) (*ParseTree, error) {
-	p := &parser{}
+	return NewParser(pCfg).Parse(args)
+}
 
-	if err := p.init(
-		strings.NewReader(strings.Join(args, string(nul))),
-		pCfg,
-	); err != nil {
-		return nil, err
+// NewParser returns a Parser for the given ParserConfig.
+func NewParser(pCfg *ParserConfig) *Parser {
+	p := &Parser{}
+	p.Reset(pCfg)
+
+	return p
+}
+
+// Reset prepares the Parser to parse according to the given
+// ParserConfig, discarding any state from previous calls to Parse.
+func (p *Parser) Reset(pCfg *ParserConfig) {
+	p.cfg = pCfg
+	p.errors = nil
+	p.tok, p.lit, p.pos = ILLEGAL, "", NoPos
+	p.buffered = false
+	p.scopedFlags, p.scopedCommands, p.scopedParents = nil, nil, nil
+	p.commandConfigs, p.flagConfigs = nil, nil
+	p.path = p.path[:0]
+
+	p.flagArena, p.identArena = arena[Flag]{}, arena[Ident]{}
+	p.nodeArena, p.valueArena = arena[Node]{}, arena[Value]{}
+
+	p.observer = nil
+	if pCfg != nil {
+		p.observer = pCfg.Observer
+	}
+
+	p.resetTracing()
+
+	if p.merged == nil {
+		p.merged = map[*Flags]map[string]*MergedFlag{}
+	}
+
+	for flags := range p.merged {
+		delete(p.merged, flags)
+	}
+}
+
+// Parse parses the given args into a ParseTree, which does not share
+// any memory with the Parser so that it remains valid after further
+// calls to Parse.
+func (p *Parser) Parse(args []string) (*ParseTree, error) {
+	p.Reset(p.cfg)
+
+	if p.cfg == nil {
+		return nil, fmt.Errorf("nil parser config: %w", Err)
 	}
 
-	tracef("ParseArgs(...) parser=%+#v", p)
+	if p.cfg.Prog == nil {
+		return nil, fmt.Errorf("nil program command config: %w", Err)
+	}
+
+	p.s.Reset(args, p.cfg.ScannerConfig)
+
+	if p.traceOn {
+		p.tracef("Parse(...) parser=%+#v", p)
+	}
+
+	p.next()
 
 	return p.parseArgs()
 }
 
-func (p *parser) addError(msg string) {
-	p.errors.Add(Position{Column: int(p.pos)}, msg)
+// addError adds the given error to those reported once parsing is
+// done, at the position of the current token unless it has one.
+func (p *Parser) addError(e *ParserError) *ParserError {
+	if !e.Pos.IsValid() {
+		e.Pos = Position{Column: int(p.pos)}
+	}
+
+	e.Path = p.errorPath()
+
+	p.errors = append(p.errors, e)
+
+	if p.observer != nil {
+		p.observer.Observe(Event{Kind: EventError, Pos: e.Pos, Node: e.Node, Err: e})
+	}
+
+	return e
+}
+
+// errorPath returns a copy of the names of the commands being parsed.
+func (p *Parser) errorPath() []string {
+	return append([]string{}, p.path...)
 }
 
-func (p *parser) init(r io.Reader, pCfg *ParserConfig) error {
-	p.errors = ParserErrorList{}
+// message returns the message of an error with the given code from
+// the configured Catalogue.
+func (p *Parser) message(code ErrorCode, args ...any) string {
+	return p.cfg.catalogue().ErrorMessage(code, args...)
+}
 
-	if pCfg == nil {
-		return fmt.Errorf("nil parser config: %w", Err)
+// countText returns the text from the configured Catalogue for the
+// number of values or positional arguments expected within the given
+// Arity, using the keys for an exact number unless it is a range.
+func (p *Parser) countText(arity Arity, one, many, atLeastOne, atLeastMany string) string {
+	cat := p.cfg.catalogue()
+
+	switch {
+	case !arity.IsRange() && arity.Min == 1:
+		return cat.Text(one)
+	case !arity.IsRange():
+		return cat.Text(many, arity.Min)
+	case arity.Min == 1:
+		return cat.Text(atLeastOne)
 	}
 
-	p.cfg = pCfg
+	return cat.Text(atLeastMany, arity.Min)
+}
 
-	p.s = NewScanner(r, pCfg.ScannerConfig)
+// flagConfigRef returns a pointer to a copy of the given FlagConfig
+// for errors, so that the config only escapes when one is reported.
+func flagConfigRef(flCfg FlagConfig) *FlagConfig {
+	return &flCfg
+}
 
-	p.next()
+// commandConfigRef returns a pointer to a copy of the given
+// CommandConfig, so that the config of each argument looked up as a
+// sub-command only escapes when one is found.
+func commandConfigRef(cCfg CommandConfig) *CommandConfig {
+	return &cCfg
+}
+
+// handlerError wraps an error returned by the handler of the given
+// node.
+func (p *Parser) handlerError(err error, kind, name string, node Node, cCfg *CommandConfig, flCfg *FlagConfig) error {
+	e := &ParserError{
+		Pos:           Position{Column: int(p.pos)},
+		Msg:           p.message(ErrHandler, kind, name, err),
+		Code:          ErrHandler,
+		Node:          node,
+		Path:          p.errorPath(),
+		CommandConfig: cCfg,
+		FlagConfig:    flCfg,
+		Err:           err,
+	}
 
-	return nil
+	if p.observer != nil {
+		p.observer.Observe(Event{Kind: EventError, Pos: e.Pos, Node: node, Err: e})
+	}
+
+	return e
 }
 
-func (p *parser) parseArgs() (*ParseTree, error) {
-	if p.errors.Len() != 0 {
-		tracef("parseArgs() bailing due to initial error")
-		return nil, p.errors.Err()
+func (p *Parser) parseArgs() (*ParseTree, error) {
+	if p.traceOn {
+		p.tracef("parseArgs() parsing %q as program command; cfg=%+#v", p.lit, p.cfg.Prog)
 	}
 
-	tracef("parseArgs() parsing %q as program command; cfg=%+#v", p.lit, p.cfg.Prog)
 	prog, err := p.parseCommand(p.cfg.Prog)
 	if err != nil {
 		return nil, err
 	}
 
-	tracef("parseArgs() top level node is %T", prog)
-
-	nodes := []Node{prog}
-	if v := p.parsePassthrough(); v != nil {
-		tracef("parseArgs() appending passthrough argument %v", v)
-		nodes = append(nodes, v)
+	if p.traceOn {
+		p.tracef("parseArgs() returning ParseTree with top level node %T", prog)
 	}
 
-	tracef("parseArgs() returning ParseTree")
+	pt := &ParseTree{
+		Nodes:          []Node{prog},
+		commandConfigs: p.commandConfigs,
+		flagConfigs:    p.flagConfigs,
+	}
 
-	return &ParseTree{Nodes: nodes}, p.errors.Err()
+	return pt, p.errors.Err()
 }
 
-func (p *parser) next() {
-	tracef("next() before scan: %v %q %v", p.tok, p.lit, p.pos)
-
+func (p *Parser) next() {
 	p.tok, p.lit, p.pos = p.s.Scan()
 
-	tracef("next() after scan: %v %q %v", p.tok, p.lit, p.pos)
+	if p.eventsOn {
+		p.event(
+			TraceScan,
+			slog.String("tok", p.tok.String()),
+			slog.String("lit", p.lit),
+			slog.Int("pos", int(p.pos)),
+		)
+	}
 }
 
-func (p *parser) parseCommand(cCfg *CommandConfig) (Node, error) {
-	tracef("parseCommand(%+#v)", cCfg)
+func (p *Parser) parseCommand(cCfg *CommandConfig) (Node, error) {
+	if p.traceOn {
+		p.tracef("parseCommand(%+#v)", cCfg)
+	}
 
 	node := &Command{
 		Name: p.lit,
 	}
-	values := map[string]string{}
-	nodes := []Node{}
+	pos := p.pos
+
+	p.path = append(p.path, node.Name)
+	defer func() { p.path = p.path[:len(p.path)-1] }()
+
+	if p.observer != nil {
+		p.observer.Observe(Event{Kind: EventCommandEnter, Pos: Position{Column: int(p.pos)}, Name: node.Name, Node: node})
+	}
+
+	if cCfg.Enter != nil {
+		if p.eventsOn {
+			p.handlerEvent("command", "Enter", node.Name)
+		}
+		if err := cCfg.Enter(&Scope{p: p, cCfg: cCfg}); err != nil {
+			return node, p.handlerError(err, "command", node.Name, node, cCfg, nil)
+		}
+	}
+
+	if p.scoped() {
+		p.recordCommand(node, cCfg)
+	}
+
+	var values map[string]string
+	var nodes []Node
 
 	identIndex := 0
+	nonInterspersed := p.cfg.NonInterspersed || cCfg.NonInterspersed
+	arity := cCfg.arity()
+	var valueList []Value
+	var positionals []Value
+	// NOTE: the arguments after a StopFlag are bound to the
+	// positional slots only once it is known whether they fit.
+	var stopped []Value
+
+	checkArity := func() {
+		if identIndex < arity.Min {
+			p.addError(&ParserError{
+				Pos:           Position{Column: int(pos)},
+				Msg:           p.message(ErrMissingPositional, node.Name, arity.Min, identIndex, p.countText(arity, TextOnePositional, TextPositionals, TextAtLeastOnePositional, TextAtLeastPositionals)),
+				Code:          ErrMissingPositional,
+				Node:          node,
+				CommandConfig: cCfg,
+			})
+		}
+	}
+
+	dispatched := false
+
+	if n := len(p.s.args) - p.s.arg; n > 0 {
+		// NOTE: each remaining argument adds at most an ArgDelimiter
+		// and one node, so that nodes need not grow.
+		nodes = make([]Node, 0, 2*n)
+	}
 
 	for i := 0; p.tok != EOL; i++ {
 		if !p.buffered {
-			tracef("parseCommand(...) buffered=false; scanning next")
+			if p.traceOn {
+				p.tracef("parseCommand(...) buffered=false; scanning next")
+			}
 			p.next()
 		}
 
 		p.buffered = false
 
-		tracef("parseCommand(...) for=%d values=%+#v", i, values)
-		tracef("parseCommand(...) for=%d nodes=%+#v", i, nodes)
-		tracef("parseCommand(...) for=%d tok=%s lit=%q pos=%v", i, p.tok, p.lit, p.pos)
+		if p.traceOn {
+			p.tracef("parseCommand(...) for=%d values=%+#v", i, values)
+			p.tracef("parseCommand(...) for=%d nodes=%+#v", i, nodes)
+			p.tracef("parseCommand(...) for=%d tok=%s lit=%q pos=%v", i, p.tok, p.lit, p.pos)
 
-		tracef("parseCommand(...) cCfg=%+#v", cCfg)
+			p.tracef("parseCommand(...) cCfg=%+#v", cCfg)
+		}
 
-		if subCfg, ok := cCfg.GetCommandConfig(p.lit); ok {
+		if subCfg, ok := p.lookupCommand(cCfg, p.lit); ok {
 			subCommand := p.lit
 
-			subNode, err := p.parseCommand(&subCfg)
+			checkArity()
+			dispatched = true
+
+			subNode, err := p.parseCommand(commandConfigRef(subCfg))
 			if err != nil {
 				return node, err
 			}
 
-			nodes = append(nodes, subNode)
+			nodes = p.appendNode(nodes, subNode)
 
-			tracef("parseCommand(...) breaking after sub-command=%v", subCommand)
+			if p.traceOn {
+				p.tracef("parseCommand(...) breaking after sub-command=%v", subCommand)
+			}
 			break
 		}
 
-		switch p.tok {
+		tok := p.tok
+
+		if p.isNumberPositional(cCfg, identIndex) {
+			if p.traceOn {
+				p.tracef("parseCommand(...) handling %s %q as negative number", p.tok, p.lit)
+			}
+			tok = IDENT
+		}
+
+		switch tok {
 		case ARG_DELIMITER:
-			tracef("parseCommand(...) handling %s", p.tok)
+			if p.traceOn {
+				p.tracef("parseCommand(...) handling %s", p.tok)
+			}
 
-			nodes = append(nodes, &ArgDelimiter{})
+			nodes = p.appendNode(nodes, &ArgDelimiter{})
 
 			continue
 		case IDENT, STDIN_FLAG:
-			tracef("parseCommand(...) handling %s", p.tok)
+			if p.traceOn {
+				p.tracef("parseCommand(...) handling %s", p.tok)
+			}
+
+			lit := p.lit
+			value := Value{Literal: lit, Pos: Position{Column: int(p.pos)}, Group: identIndex}
 
-			if cCfg.NValue.Contains(identIndex) {
-				name := fmt.Sprintf("%d", identIndex)
+			if nonInterspersed {
+				// NOTE: the first positional argument is taken
+				// verbatim just like those that follow it.
+				lit = p.scanRawArg()
+				value.Literal = lit
+				value.Pos = p.rawArgEnd()
+			}
+
+			if arity.Contains(identIndex) && len(cCfg.Positionals) > 0 {
+				if p.traceOn {
+					p.tracef("parseCommand(...) deferring binding of positional identIndex=%d", identIndex)
+				}
 
-				tracef("parseCommand(...) checking for name of identIndex=%d", identIndex)
+				positionals = append(positionals, value)
+			} else if arity.Contains(identIndex) {
+				name := strconv.Itoa(identIndex)
+
+				if p.traceOn {
+					p.tracef("parseCommand(...) checking for name of identIndex=%d", identIndex)
+				}
 
 				if len(cCfg.ValueNames) > identIndex {
 					name = cCfg.ValueNames[identIndex]
-					tracef("parseCommand(...) setting name=%s from config value names", name)
-				} else if len(cCfg.ValueNames) == 1 && (cCfg.NValue == OneOrMoreValue || cCfg.NValue == ZeroOrMoreValue) {
+					if p.traceOn {
+						p.tracef("parseCommand(...) setting name=%s from config value names", name)
+					}
+				} else if len(cCfg.ValueNames) == 1 && arity.IsRange() {
 					name = fmt.Sprintf("%s.%d", cCfg.ValueNames[0], identIndex)
-					tracef("parseCommand(...) setting name=%s from repeating value name", name)
+					if p.traceOn {
+						p.tracef("parseCommand(...) setting name=%s from repeating value name", name)
+					}
 				}
 
-				values[name] = p.lit
+				if values == nil {
+					values = map[string]string{}
+				}
+
+				values[name] = lit
+
+				value.Name = name
+				valueList = append(valueList, value)
+			}
+
+			if p.observer != nil {
+				p.observer.Observe(Event{Kind: EventPositional, Pos: value.Pos, Name: value.Name, Node: node, Value: value})
+			}
+
+			if !arity.Contains(identIndex) && p.cfg.Strict {
+				p.addExcessPositionalError(cCfg, lit)
 			}
 
-			if p.tok == STDIN_FLAG {
-				nodes = append(nodes, &StdinFlag{})
+			if tok == STDIN_FLAG {
+				nodes = p.appendNode(nodes, &StdinFlag{})
 			} else {
-				nodes = append(nodes, &Ident{Literal: p.lit})
+				nodes = p.appendNode(nodes, p.newIdent(lit))
 			}
 
 			identIndex++
-		case LONG_FLAG, SHORT_FLAG, COMPOUND_SHORT_FLAG:
-			tok := p.tok
 
-			flagNode, err := p.parseFlag(cCfg.Flags)
+			if nonInterspersed {
+				if p.traceOn {
+					p.tracef("parseCommand(...) non-interspersed after positional; parsing passthrough")
+				}
+
+				if p.tok == ARG_DELIMITER {
+					nodes = p.appendNode(nodes, &ArgDelimiter{})
+				}
+
+				if v := p.parsePassthrough(nil); v != nil {
+					if p.traceOn {
+						p.tracef("parseCommand(...) appending passthrough arguments %+#v", v)
+					}
+					nodes = p.appendNode(nodes, v)
+				}
+			}
+		case LONG_FLAG, SHORT_FLAG, COMPOUND_SHORT_FLAG:
+			flagNode, err := p.parseFlag(cCfg)
 			if err != nil {
 				return node, err
 			}
 
-			tracef("parseCommand(...) appending %s node=%+#v", tok, flagNode)
+			if p.traceOn {
+				p.tracef("parseCommand(...) appending %s node=%+#v", tok, flagNode)
+			}
+
+			nodes = p.appendNode(nodes, flagNode)
+		case STOP_FLAG:
+			if p.traceOn {
+				p.tracef("parseCommand(...) handling %s", p.tok)
+			}
+
+			nodes = p.appendNode(nodes, &StopFlag{})
 
-			nodes = append(nodes, flagNode)
+			p.next()
+
+			if p.tok == ARG_DELIMITER {
+				nodes = p.appendNode(nodes, &ArgDelimiter{})
+			}
+
+			var bind func(Value)
+			if len(cCfg.Positionals) > 0 {
+				bind = func(value Value) {
+					stopped = append(stopped, value)
+				}
+			}
+
+			if v := p.parsePassthrough(bind); v != nil {
+				if p.traceOn {
+					p.tracef("parseCommand(...) appending passthrough arguments %+#v", v)
+				}
+				nodes = p.appendNode(nodes, v)
+			}
 		case ASSIGN:
-			tracef("parseCommand(...) error on bare %s", p.tok)
+			if p.traceOn {
+				p.tracef("parseCommand(...) error on bare %s", p.tok)
+			}
 
-			p.addError("invalid bare assignment")
+			p.addError(&ParserError{
+				Msg:           p.message(ErrBareAssignment),
+				Code:          ErrBareAssignment,
+				Node:          &Assign{},
+				CommandConfig: cCfg,
+			})
 
 			break
 		default:
-			tracef("parseCommand(...) breaking on %s", p.tok)
+			if p.traceOn {
+				p.tracef("parseCommand(...) breaking on %s", p.tok)
+			}
 			break
 		}
 	}
 
+	var names []string
+
+	if len(stopped) > 0 {
+		if stoppedNames, ok := cCfg.bindStoppedPositionals(len(positionals), len(stopped)); ok {
+			names = stoppedNames
+
+			for _, value := range stopped {
+				value.Group = identIndex
+				positionals = append(positionals, value)
+				identIndex++
+			}
+		}
+	}
+
+	if names == nil && len(positionals) > 0 {
+		names = cCfg.bindPositionals(len(positionals))
+	}
+
+	if !dispatched {
+		checkArity()
+	}
+
+	if len(positionals) > 0 {
+		for i, name := range names {
+			positionals[i].Name = name
+			if values == nil {
+				values = map[string]string{}
+			}
+
+			values[name] = positionals[i].Literal
+		}
+
+		valueList = append(valueList, positionals...)
+	}
+
 	if len(nodes) > 0 {
 		node.Nodes = nodes
 	}
@@ -195,99 +551,218 @@ func (p *parser) parseCommand(cCfg *CommandConfig) (Node, error) {
 		node.Values = values
 	}
 
+	if len(valueList) > 0 {
+		node.ValueList = valueList
+	}
+
+	if merged := p.merged[cCfg.Flags]; len(merged) > 0 {
+		node.Merged = map[string]MergedFlag{}
+
+		for name, mergedFlag := range merged {
+			node.Merged[name] = *mergedFlag
+		}
+	}
+
+	if p.observer != nil {
+		p.observer.Observe(Event{Kind: EventCommandExit, Pos: Position{Column: int(p.pos)}, Name: node.Name, Node: node})
+	}
+
+	if cCfg.Exit != nil {
+		if p.eventsOn {
+			p.handlerEvent("command", "Exit", node.Name)
+		}
+		if err := cCfg.Exit(&Scope{p: p, cCfg: cCfg}); err != nil {
+			return node, p.handlerError(err, "command", node.Name, node, cCfg, nil)
+		}
+	}
+
 	if cCfg.On != nil {
-		tracef("parseCommand(...) calling command config handler for node=%+#v", node)
+		if p.eventsOn {
+			p.handlerEvent("command", "On", node.Name)
+		}
 		if err := cCfg.On(*node); err != nil {
-			return node, err
+			return node, p.handlerError(err, "command", node.Name, node, cCfg, nil)
 		}
 	} else {
-		tracef("parseCommand(...) no command config handler for node=%+#v", node)
+		if p.traceOn {
+			p.tracef("parseCommand(...) no command config handler for node=%+#v", node)
+		}
 	}
 
-	tracef("parseCommand(...) returning node=%+#v", node)
+	if p.traceOn {
+		p.tracef("parseCommand(...) returning node=%+#v", node)
+	}
 	return node, nil
 }
 
-func (p *parser) parseIdent() Node {
+// addExcessPositionalError reports the given positional argument as
+// exceeding those expected by the command, or as an unknown command
+// when the command only expects sub-commands.
+func (p *Parser) addExcessPositionalError(cCfg *CommandConfig, lit string) {
+	if cCfg.arity().Max == 0 && cCfg.Commands != nil && len(cCfg.Commands.Map) > 0 {
+		p.addError(&ParserError{
+			Msg:           p.message(ErrUnknownCommand, lit, strings.Join(cCfg.Commands.Names(), ", ")),
+			Code:          ErrUnknownCommand,
+			Node:          &Ident{Literal: lit},
+			CommandConfig: cCfg,
+		})
+
+		return
+	}
+
+	p.addError(&ParserError{
+		Msg:           p.message(ErrUnexpectedArgument, lit),
+		Code:          ErrUnexpectedArgument,
+		Node:          &Ident{Literal: lit},
+		CommandConfig: cCfg,
+	})
+}
+
+// isNumberValue returns whether the current token is a flag-prefixed
+// number to be parsed as a value, where required is true when a
+// value must be given at this point.
+func (p *Parser) isNumberValue(flags *Flags, required bool) bool {
+	if p.tok != SHORT_FLAG && p.tok != COMPOUND_SHORT_FLAG {
+		return false
+	}
+
+	if p.cfg.NegativeNumbers == NegativeNumbersAsFlags || !p.s.cfg.IsNegativeNumber(p.lit) {
+		return false
+	}
+
+	return required || !flags.hasDigitShortFlags()
+}
+
+// isNumberPositional returns whether the current token is a
+// flag-prefixed number to be parsed as the positional argument at
+// the given index.
+func (p *Parser) isNumberPositional(cCfg *CommandConfig, identIndex int) bool {
+	switch p.cfg.NegativeNumbers {
+	case NegativeNumbersAsValues:
+		return cCfg.arity().Contains(identIndex) && p.isNumberValue(cCfg.Flags, false)
+	case NegativeNumbersAnywhere:
+		return p.isNumberValue(cCfg.Flags, false)
+	}
+
+	return false
+}
+
+func (p *Parser) parseIdent() Node {
 	node := &Ident{Literal: p.lit}
 	return node
 }
 
-func (p *parser) parseFlag(flags *Flags) (Node, error) {
+func (p *Parser) parseFlag(cCfg *CommandConfig) (Node, error) {
 	switch p.tok {
 	case SHORT_FLAG:
-		tracef("parseFlag(...) parsing short flag with config=%+#v", flags)
-		return p.parseShortFlag(flags)
+		if p.traceOn {
+			p.tracef("parseFlag(...) parsing short flag with config=%+#v", cCfg.Flags)
+		}
+		return p.parseShortFlag(cCfg)
 	case LONG_FLAG:
-		tracef("parseFlag(...) parsing long flag with config=%+#v", flags)
-		return p.parseLongFlag(flags)
+		if p.traceOn {
+			p.tracef("parseFlag(...) parsing long flag with config=%+#v", cCfg.Flags)
+		}
+		return p.parseLongFlag(cCfg)
 	case COMPOUND_SHORT_FLAG:
-		tracef("parseFlag(...) parsing compound short flag with config=%+#v", flags)
-		return p.parseCompoundShortFlag(flags)
+		if p.traceOn {
+			p.tracef("parseFlag(...) parsing compound short flag with config=%+#v", cCfg.Flags)
+		}
+		return p.parseCompoundShortFlag(cCfg)
 	}
 
 	panic(fmt.Sprintf("token %v cannot be parsed as flag", p.tok))
 }
 
-func (p *parser) parseShortFlag(flags *Flags) (Node, error) {
-	node := &Flag{Name: string(p.lit[1])}
+func (p *Parser) parseShortFlag(cCfg *CommandConfig) (Node, error) {
+	node := p.newFlag(p.lit[1:])
 
-	flCfg, ok := flags.Get(node.Name)
+	flCfg, ok := p.lookupFlag(cCfg, node.Name)
 	if !ok {
-		errMsg := fmt.Sprintf("unknown flag %[1]q", node.Name)
-		p.addError(errMsg)
-
-		return node, &FlagError{
-			Pos:  Position{Column: int(p.pos)},
-			Node: *node,
-			Msg:  errMsg,
+		if cCfg.Flags.passthroughUnknown() {
+			return p.parseUnknownFlag(), nil
 		}
+
+		return node, p.addUnknownFlagError(cCfg, node)
 	}
 
-	return p.parseConfiguredFlag(node, flCfg, nil)
+	return p.parseConfiguredFlag(cCfg, node, flCfg, nil, "")
 }
 
-func (p *parser) parseLongFlag(flags *Flags) (Node, error) {
-	node := &Flag{Name: string(p.lit[2:])}
+func (p *Parser) parseLongFlag(cCfg *CommandConfig) (Node, error) {
+	node := p.newFlag(p.lit[2:])
 
-	flCfg, ok := flags.Get(node.Name)
-	if !ok {
-		errMsg := fmt.Sprintf("unknown flag %[1]q", node.Name)
-		p.addError(errMsg)
+	if name, flCfg, ok := p.lookupNegatedFlag(cCfg, node.Name); ok {
+		if p.traceOn {
+			p.tracef("parseLongFlag(...) parsing %q as negated flag %q", node.Name, name)
+		}
+
+		node.Name = name
+		node.Negated = true
 
-		return node, &FlagError{
-			Pos:  Position{Column: int(p.pos)},
-			Node: *node,
-			Msg:  errMsg,
+		return p.parseConfiguredFlag(cCfg, node, flCfg, zeroValuePtr, "")
+	}
+
+	flCfg, ok := p.lookupFlag(cCfg, node.Name)
+	if !ok {
+		if cCfg.Flags.passthroughUnknown() {
+			return p.parseUnknownFlag(), nil
 		}
+
+		return node, p.addUnknownFlagError(cCfg, node)
 	}
 
-	return p.parseConfiguredFlag(node, flCfg, nil)
+	return p.parseConfiguredFlag(cCfg, node, flCfg, nil, "")
 }
 
-func (p *parser) parseCompoundShortFlag(flags *Flags) (Node, error) {
+func (p *Parser) parseCompoundShortFlag(cCfg *CommandConfig) (Node, error) {
 	unparsedFlags := []*Flag{}
 	unparsedFlagConfigs := []FlagConfig{}
 
 	withoutFlagPrefix := p.lit[1:]
+	attached := ""
+	unknown := ""
 
-	for _, r := range withoutFlagPrefix {
-		node := &Flag{Name: string(r)}
+	for i, r := range withoutFlagPrefix {
+		node := p.newFlag(withoutFlagPrefix[i : i+utf8.RuneLen(r)])
 
-		flCfg, ok := flags.Get(node.Name)
+		flCfg, ok := p.lookupFlag(cCfg, node.Name)
 		if !ok {
-			errMsg := fmt.Sprintf("unknown flag %[1]q", node.Name)
-			p.addError(errMsg)
+			if cCfg.Flags.passthroughUnknown() && i == 0 {
+				return p.parseUnknownFlag(), nil
+			}
 
-			return node, &FlagError{
-				Pos:  Position{Column: int(p.pos)},
-				Node: *node,
-				Msg:  errMsg,
+			if cCfg.Flags.passthroughUnknown() {
+				// NOTE: the flags before the first unknown one are
+				// parsed, while the remainder of the compound group
+				// is passed through, e.g. "-w" of "-vw".
+				unknown = p.lit[:1] + withoutFlagPrefix[i:]
+
+				if p.traceOn {
+					p.tracef("parseCompoundShortFlag(...) passing through unknown remainder %q", unknown)
+				}
+				break
 			}
+
+			return node, p.addUnknownFlagError(cCfg, node)
 		}
 
 		unparsedFlags = append(unparsedFlags, node)
 		unparsedFlagConfigs = append(unparsedFlagConfigs, flCfg)
+
+		if flCfg.AttachedValue || flCfg.arity().Required() {
+			// NOTE: the remainder of the compound group is the
+			// directly attached value of the first flag that expects
+			// values, e.g. "-ofile.txt" for "-o file.txt", or of a
+			// flag that only accepts attached values, e.g. "-calways"
+			// for "-c=always".
+			attached = withoutFlagPrefix[i+utf8.RuneLen(r):]
+
+			if p.traceOn {
+				p.tracef("parseCompoundShortFlag(...) flag %q has attached value %q", node.Name, attached)
+			}
+			break
+		}
 	}
 
 	flagNodes := []Node{}
@@ -295,24 +770,12 @@ func (p *parser) parseCompoundShortFlag(flags *Flags) (Node, error) {
 	for i, node := range unparsedFlags {
 		flCfg := unparsedFlagConfigs[i]
 
-		if i != len(unparsedFlags)-1 {
+		if i != len(unparsedFlags)-1 || unknown != "" {
 			// NOTE: if a compound short flag is configured to accept
-			// more than zero values but is not the last flag in the
+			// zero or more values but is not the last flag in the
 			// group, it will be parsed with an override NValue of
 			// ZeroValue so that it does not consume the next token.
-			if flCfg.NValue.Required() {
-				errMsg := fmt.Sprintf(
-					"short flag %[1]q before end of compound group expects value",
-					node.Name,
-				)
-				p.addError(
-					errMsg,
-				)
-
-				return nil, fmt.Errorf(errMsg+": %[1]w", Err)
-			}
-
-			flagNode, err := p.parseConfiguredFlag(node, flCfg, zeroValuePtr)
+			flagNode, err := p.parseConfiguredFlag(cCfg, node, flCfg, zeroValuePtr, "")
 			if err != nil {
 				return nil, err
 			}
@@ -322,7 +785,7 @@ func (p *parser) parseCompoundShortFlag(flags *Flags) (Node, error) {
 			continue
 		}
 
-		flagNode, err := p.parseConfiguredFlag(node, flCfg, nil)
+		flagNode, err := p.parseConfiguredFlag(cCfg, node, flCfg, nil, attached)
 		if err != nil {
 			return nil, err
 		}
@@ -330,109 +793,493 @@ func (p *parser) parseCompoundShortFlag(flags *Flags) (Node, error) {
 		flagNodes = append(flagNodes, flagNode)
 	}
 
+	if unknown != "" {
+		p.next()
+
+		if p.tok != ARG_DELIMITER && p.tok != EOL {
+			unknown += p.scanRawArg()
+		}
+
+		flagNodes = append(flagNodes, p.passUnknownFlag(unknown))
+	}
+
 	return &CompoundShortFlag{Nodes: flagNodes}, nil
 }
 
-func (p *parser) parseConfiguredFlag(node *Flag, flCfg FlagConfig, nValueOverride *NValue) (Node, error) {
-	values := map[string]string{}
-	nodes := []Node{}
+func (p *Parser) addUnknownFlagError(cCfg *CommandConfig, node *Flag) error {
+	return p.addError(&ParserError{
+		Msg:           p.message(ErrUnknownFlag, node.Name),
+		Code:          ErrUnknownFlag,
+		Node:          node,
+		CommandConfig: cCfg,
+	})
+}
+
+// parseUnknownFlag parses the current argument verbatim as an
+// UnknownFlag, including any values joined to it.
+func (p *Parser) parseUnknownFlag() Node {
+	return p.passUnknownFlag(p.scanRawArg())
+}
+
+// passUnknownFlag returns an UnknownFlag for the given verbatim
+// literal, after which the parser is left at the next ARG_DELIMITER
+// or EOL.
+func (p *Parser) passUnknownFlag(lit string) Node {
+	node := &UnknownFlag{Literal: lit}
+
+	if p.observer != nil {
+		p.observePassthrough(node, node.Literal)
+	}
+
+	if p.traceOn {
+		p.tracef("passUnknownFlag(...) passing through %q; setting buffered=true", node.Literal)
+	}
+	p.buffered = true
+
+	return node
+}
+
+// parseConfiguredFlag parses the values of the given flag node
+// according to its config. A non-empty attached string is taken as
+// the first value, as when the value is directly attached to a short
+// flag in a compound group.
+func (p *Parser) parseConfiguredFlag(cCfg *CommandConfig, node *Flag, flCfg FlagConfig, nValueOverride *NValue, attached string) (Node, error) {
+	var values map[string]string
+	var nodes []Node
+	pos := p.pos
+	arity := flCfg.arity()
+	identIndex := 0
+	var valueList []Value
+	source := ValueFromArg
+	group := -1
 
 	atExit := func() (*Flag, error) {
 		if len(nodes) > 0 {
 			node.Nodes = nodes
 		}
 
+		if flCfg.Negatable && len(values) == 0 {
+			name := "0"
+			if len(flCfg.ValueNames) > 0 {
+				name = flCfg.ValueNames[0]
+			}
+
+			if values == nil {
+				values = map[string]string{}
+			}
+
+			values[name] = strconv.FormatBool(!node.Negated)
+			valueList = append(valueList, Value{
+				Name:    name,
+				Literal: values[name],
+				Pos:     Position{Column: int(pos)},
+				Source:  ValueFromFlag,
+			})
+		}
+
 		if len(values) > 0 {
 			node.Values = values
 		}
 
+		if len(valueList) > 0 {
+			node.ValueList = valueList
+		}
+
+		if p.observer != nil {
+			p.observer.Observe(Event{Kind: EventFlag, Pos: Position{Column: int(pos)}, Name: node.Name, Node: node})
+
+			for _, value := range valueList {
+				p.observer.Observe(Event{Kind: EventValue, Pos: value.Pos, Name: node.Name, Node: node, Value: value})
+			}
+		}
+
+		if nValueOverride == nil && !flCfg.AttachedValue && identIndex < arity.Min {
+			p.addError(&ParserError{
+				Pos:           Position{Column: int(pos)},
+				Msg:           p.message(ErrMissingValue, node.Name, arity.Min, identIndex, p.countText(arity, TextOneValue, TextValues, TextAtLeastOneValue, TextAtLeastValues)),
+				Code:          ErrMissingValue,
+				Node:          node,
+				CommandConfig: cCfg,
+				FlagConfig:    flagConfigRef(flCfg),
+			})
+		}
+
 		if flCfg.On != nil {
-			tracef("parseConfiguredFlag(...) calling flag config handler for node=%+#[1]v", node)
+			if p.eventsOn {
+				p.handlerEvent("flag", "On", node.Name)
+			}
 			if err := flCfg.On(*node); err != nil {
-				return nil, err
+				return nil, p.handlerError(err, "flag", node.Name, node, cCfg, flagConfigRef(flCfg))
 			}
 		} else {
-			tracef("parseConfiguredFlag(...) no flag config handler for node=%+#[1]v", node)
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) no flag config handler for node=%+#[1]v", node)
+			}
+		}
+
+		if flCfg.Configure != nil {
+			if p.eventsOn {
+				p.handlerEvent("flag", "Configure", node.Name)
+			}
+			if err := flCfg.Configure(*node, &Scope{p: p, cCfg: cCfg}); err != nil {
+				return nil, p.handlerError(err, "flag", node.Name, node, cCfg, flagConfigRef(flCfg))
+			}
 		}
 
+		if p.scoped() {
+			p.recordFlag(cCfg, node, flCfg)
+		}
+
+		p.mergeFlag(cCfg, node, flCfg, pos)
+
 		return node, nil
 	}
 
-	identIndex := 0
-
-	for i := 0; p.tok != EOL; i++ {
+	expectsValue := func() bool {
 		if nValueOverride != nil && !(*nValueOverride).Contains(identIndex) {
-			tracef("parseConfiguredFlag(...) identIndex=%d exceeds expected=%v; breaking", identIndex, *nValueOverride)
-			break
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) identIndex=%d exceeds expected=%v; breaking", identIndex, *nValueOverride)
+			}
+			return false
 		}
 
-		if !flCfg.NValue.Contains(identIndex) {
-			tracef("parseConfiguredFlag(...) identIndex=%d exceeds expected=%v; breaking", identIndex, flCfg.NValue)
-			break
+		if !arity.Contains(identIndex) {
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) identIndex=%d exceeds expected=%v; breaking", identIndex, arity)
+			}
+			return false
 		}
 
+		return true
+	}
+
+	addValue := func(tok Token, lit string, valuePos Position) {
+		name := strconv.Itoa(identIndex)
+
+		if p.traceOn {
+			p.tracef("parseConfiguredFlag(...) checking for name of identIndex=%d", identIndex)
+		}
+
+		if len(flCfg.ValueNames) > identIndex {
+			name = flCfg.ValueNames[identIndex]
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) setting name=%s from config value names", name)
+			}
+		} else if len(flCfg.ValueNames) == 1 && arity.IsRange() {
+			name = fmt.Sprintf("%s.%d", flCfg.ValueNames[0], identIndex)
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) setting name=%s from repeating value name", name)
+			}
+		} else {
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) setting name=%s", name)
+			}
+		}
+
+		if tok != MULTI_VALUE_DELIMITER {
+			if values == nil {
+				values = map[string]string{}
+			}
+
+			values[name] = lit
+		}
+
+		// addNode adds the given node to the multi-value list being
+		// parsed, if any, returning whether the list already held a
+		// value so that the value belongs to the same group.
+		addNode := func(node Node) bool {
+			if len(nodes) > 0 {
+				if v, ok := nodes[len(nodes)-1].(*MultiIdent); ok {
+					v.Nodes = append(v.Nodes, node)
+					return len(v.Nodes) > 1
+				}
+			}
+
+			nodes = append(nodes, node)
+			return false
+		}
+
+		inList := false
+
+		if tok == STDIN_FLAG {
+			inList = addNode(&StdinFlag{})
+		} else if tok == MULTI_VALUE_DELIMITER {
+			if len(nodes) > 0 {
+				if v, ok := nodes[len(nodes)-1].(*Ident); ok {
+					nodes[len(nodes)-1] = &MultiIdent{Nodes: []Node{v}}
+				} else if v, ok := nodes[len(nodes)-1].(*StdinFlag); ok {
+					nodes[len(nodes)-1] = &MultiIdent{Nodes: []Node{v}}
+				}
+			} else {
+				nodes = append(nodes, &MultiIdent{Nodes: []Node{}})
+			}
+		} else {
+			inList = addNode(p.newIdent(lit))
+		}
+
+		if tok != MULTI_VALUE_DELIMITER {
+			if !inList {
+				group++
+			}
+
+			if valueList == nil {
+				valueList = p.valueArena.slice(1)
+			}
+
+			valueList = append(valueList, Value{
+				Name:    name,
+				Literal: lit,
+				Pos:     valuePos,
+				Source:  source,
+				Group:   group,
+			})
+
+			identIndex++
+		}
+	}
+
+	if flCfg.KeyValue && nValueOverride == nil {
+		// NOTE: the argument holding the pairs is taken verbatim so
+		// that the assignment operators within each pair are not
+		// parsed as belonging to the flag.
+		raw := attached
+
 		p.next()
 
+		if attached != "" {
+			source = ValueFromAttached
+		}
+
 		switch p.tok {
+		case ASSIGN:
+			if attached == "" {
+				nodes = append(nodes, &Assign{})
+				source = ValueFromAssign
+				p.next()
+			}
+
+			raw += p.scanRawArg()
 		case ARG_DELIMITER:
+			if attached != "" {
+				break
+			}
+
 			nodes = append(nodes, &ArgDelimiter{})
+			p.next()
 
-			continue
-		case ASSIGN:
-			nodes = append(nodes, &Assign{})
+			if p.tok == IDENT || p.tok == MULTI_VALUE_DELIMITER {
+				raw = p.scanRawArg()
+			}
+		case IDENT, MULTI_VALUE_DELIMITER:
+			raw += p.scanRawArg()
+		}
 
-			continue
-		case IDENT, STDIN_FLAG, MULTI_VALUE_DELIMITER:
-			name := fmt.Sprintf("%d", identIndex)
+		if p.traceOn {
+			p.tracef("parseConfiguredFlag(...) parsed key=value argument %q; setting buffered=true", raw)
+		}
+		p.buffered = true
+
+		if raw == "" {
+			return atExit()
+		}
 
-			tracef("parseConfiguredFlag(...) checking for name of identIndex=%d", identIndex)
+		items := []Node{}
+		rawItems := p.splitValues(flCfg, raw)
+		itemEnds := splitEnds(rawItems, p.rawArgEnd())
 
-			if len(flCfg.ValueNames) > identIndex {
-				name = flCfg.ValueNames[identIndex]
-				tracef("parseConfiguredFlag(...) setting name=%s from config value names", name)
-			} else if len(flCfg.ValueNames) == 1 && (flCfg.NValue == OneOrMoreValue || flCfg.NValue == ZeroOrMoreValue) {
-				name = fmt.Sprintf("%s.%d", flCfg.ValueNames[0], identIndex)
-				tracef("parseConfiguredFlag(...) setting name=%s from repeating value name", name)
-			} else {
-				tracef("parseConfiguredFlag(...) setting name=%s", name)
+		for i, item := range rawItems {
+			items = append(items, &Ident{Literal: item})
+
+			key, value, ok := strings.Cut(item, string(p.s.cfg.AssignmentOperator))
+			if !ok || key == "" {
+				p.addError(&ParserError{
+					Msg:           p.message(ErrInvalidKeyValue, item, node.Name),
+					Code:          ErrInvalidKeyValue,
+					Node:          node,
+					CommandConfig: cCfg,
+					FlagConfig:    flagConfigRef(flCfg),
+				})
+
+				continue
 			}
 
-			if p.tok != MULTI_VALUE_DELIMITER {
-				values[name] = p.lit
+			if values == nil {
+				values = map[string]string{}
 			}
 
-			addNode := func(node Node) {
-				if len(nodes) > 0 {
-					if v, ok := nodes[len(nodes)-1].(*MultiIdent); ok {
-						v.Nodes = append(v.Nodes, node)
-						return
-					}
+			values[key] = value
+			valueList = append(valueList, Value{
+				Name:    key,
+				Literal: value,
+				Pos:     itemEnds[i],
+				Source:  source,
+			})
+			node.Pairs = append(node.Pairs, Pair{Key: key, Value: value})
+		}
+
+		if len(items) == 1 {
+			nodes = append(nodes, items[0])
+		} else {
+			nodes = append(nodes, &MultiIdent{Nodes: items, Delim: flCfg.MultiValueDelim})
+		}
+
+		identIndex++
+
+		return atExit()
+	}
+
+	// addRawValue adds each item of a verbatim argument split
+	// according to the multi-value delimiter of the flag.
+	addRawValue := func(lit string) {
+		items := p.splitValues(flCfg, lit)
+		if len(items) > 1 {
+			nodes = append(nodes, &MultiIdent{Nodes: []Node{}, Delim: flCfg.MultiValueDelim})
+		}
+
+		itemEnds := splitEnds(items, p.rawArgEnd())
+
+		for i, item := range items {
+			addValue(IDENT, item, itemEnds[i])
+		}
+
+		if p.traceOn {
+			p.tracef("parseConfiguredFlag(...) added raw value %q; setting buffered=true", lit)
+		}
+		p.buffered = true
+	}
+
+	if attached != "" {
+		source = ValueFromAttached
+	}
+
+	if attached != "" {
+		// NOTE: the rest of the argument is taken verbatim, so that
+		// e.g. "-ofoo=bar" gives the value "foo=bar".
+		p.next()
+
+		if p.tok != ARG_DELIMITER && p.tok != EOL {
+			attached += p.scanRawArg()
+		}
+
+		if flCfg.MultiValueDelim != 0 {
+			addRawValue(attached)
+		} else {
+			addValue(IDENT, attached, p.rawArgEnd())
+
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) added attached value %q; setting buffered=true", attached)
+			}
+			p.buffered = true
+		}
+	} else if flCfg.AttachedValue && expectsValue() {
+		// NOTE: a flag that only accepts attached values takes a value
+		// only when joined via the assignment operator, leaving any
+		// following argument to be parsed on its own.
+		p.next()
+
+		if p.tok != ASSIGN {
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) no attached value on %s %q %v; setting buffered=true", p.tok, p.lit, p.pos)
+			}
+			p.buffered = true
+
+			return atExit()
+		}
+
+		nodes = append(nodes, &Assign{})
+		source = ValueFromAssign
+	}
+
+	if nodes == nil && p.tok != EOL && expectsValue() {
+		// NOTE: a flag given a single value has an ArgDelimiter on
+		// either side of it, for which room is made at once.
+		nodes = p.nodeArena.slice(3)
+	}
+
+	for i := 0; p.tok != EOL; i++ {
+		if !expectsValue() {
+			break
+		}
+
+		if p.buffered {
+			p.buffered = false
+		} else {
+			p.next()
+		}
+
+		tok := p.tok
+
+		// NOTE: a value is required directly after an assignment
+		// operator as well as until the minimum count is reached.
+		afterAssign := false
+		if len(nodes) > 0 {
+			_, afterAssign = nodes[len(nodes)-1].(*Assign)
+		}
+
+		required := afterAssign || identIndex < arity.Min
+
+		if p.isNumberValue(cCfg.Flags, required) {
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) handling %s %q as negative number", p.tok, p.lit)
+			}
+			tok = IDENT
+		}
+
+		switch tok {
+		case ARG_DELIMITER:
+			if flCfg.AttachedValue {
+				if p.traceOn {
+					p.tracef("parseConfiguredFlag(...) end of attached value; setting buffered=true")
 				}
+				p.buffered = true
 
-				nodes = append(nodes, node)
+				return atExit()
 			}
 
-			if p.tok == STDIN_FLAG {
-				addNode(&StdinFlag{})
-			} else if p.tok == MULTI_VALUE_DELIMITER {
-				if len(nodes) > 0 {
-					if v, ok := nodes[len(nodes)-1].(*Ident); ok {
-						nodes[len(nodes)-1] = &MultiIdent{Nodes: []Node{v}}
-					} else if v, ok := nodes[len(nodes)-1].(*StdinFlag); ok {
-						nodes[len(nodes)-1] = &MultiIdent{Nodes: []Node{v}}
+			nodes = append(nodes, &ArgDelimiter{})
+			source = ValueFromArg
+
+			continue
+		case ASSIGN:
+			nodes = append(nodes, &Assign{})
+			source = ValueFromAssign
+
+			continue
+		case IDENT, STDIN_FLAG, MULTI_VALUE_DELIMITER:
+			if tok == IDENT && flCfg.Terminator != "" && p.lit == flCfg.Terminator {
+				if p.traceOn {
+					p.tracef("parseConfiguredFlag(...) ending values at terminator %q", p.lit)
+				}
+				nodes = append(nodes, &ListTerminator{Literal: p.lit})
+
+				return atExit()
+			}
+
+			// NOTE: a sub-command ends the values of the flag even
+			// when too few were given, which is then reported,
+			// unless joined to the flag via the assignment operator.
+			if tok == IDENT && !afterAssign {
+				if _, ok := p.lookupCommand(cCfg, p.lit); ok {
+					if p.traceOn {
+						p.tracef("parseConfiguredFlag(...) yielding to sub-command %q; setting buffered=true", p.lit)
 					}
-				} else {
-					nodes = append(nodes, &MultiIdent{Nodes: []Node{}})
+					p.buffered = true
+
+					return atExit()
 				}
-			} else {
-				addNode(&Ident{Literal: p.lit})
 			}
 
-			if p.tok != MULTI_VALUE_DELIMITER {
-				identIndex++
+			if flCfg.MultiValueDelim != 0 && tok != STDIN_FLAG {
+				addRawValue(p.scanRawArg())
+
+				continue
 			}
+
+			addValue(tok, p.lit, Position{Column: int(p.pos)})
 		default:
-			tracef("parseConfiguredFlag(...) breaking on %s %q %v; setting buffered=true", p.tok, p.lit, p.pos)
+			if p.traceOn {
+				p.tracef("parseConfiguredFlag(...) breaking on %s %q %v; setting buffered=true", p.tok, p.lit, p.pos)
+			}
 			p.buffered = true
 
 			return atExit()
@@ -442,11 +1289,141 @@ func (p *parser) parseConfiguredFlag(node *Flag, flCfg FlagConfig, nValueOverrid
 	return atExit()
 }
 
-func (p *parser) parsePassthrough() Node {
+// splitEnds returns the position of the last rune of each of the
+// given items split from a verbatim argument ending at the given
+// position, where the items were separated by a single rune.
+func splitEnds(items []string, end Position) []Position {
+	n := len(items) - 1
+	for _, item := range items {
+		n += utf8.RuneCountInString(item)
+	}
+
+	ends := make([]Position, len(items))
+	column := end.Column - n
+
+	for i, item := range items {
+		column += utf8.RuneCountInString(item)
+		ends[i] = Position{Column: column}
+		column++
+	}
+
+	return ends
+}
+
+// splitValues splits the given verbatim argument into a multi-value
+// list according to the multi-value delimiter of the flag, which
+// defaults to that of the scanner.
+func (p *Parser) splitValues(flCfg FlagConfig, lit string) []string {
+	switch flCfg.MultiValueDelim {
+	case NoMultiValueDelim:
+		return []string{lit}
+	case 0:
+		return strings.Split(lit, string(p.s.cfg.MultiValueDelim))
+	}
+
+	return strings.Split(lit, string(flCfg.MultiValueDelim))
+}
+
+// mergeFlag folds the given occurrence of a flag into the merged
+// view of the command in which the flag is configured according to
+// its RepeatMode.
+func (p *Parser) mergeFlag(cCfg *CommandConfig, node *Flag, flCfg FlagConfig, pos Pos) {
+	repeat := flCfg.repeat()
+	if repeat == RepeatEach {
+		return
+	}
+
+	literals := p.flagLiterals(node)
+
+	_, owner, _ := p.lookupFlagOwner(cCfg.Flags, node.Name)
+
+	if p.merged[owner] == nil {
+		p.merged[owner] = map[string]*MergedFlag{}
+	}
+
+	mergedFlag, ok := p.merged[owner][node.Name]
+	if !ok {
+		mergedFlag = &MergedFlag{Name: node.Name}
+		p.merged[owner][node.Name] = mergedFlag
+	}
+
+	if p.traceOn {
+		p.tracef("mergeFlag(...) merging occurrence %d of flag %q", mergedFlag.Count+1, node.Name)
+	}
+
+	mergedFlag.Count++
+
+	switch repeat {
+	case RepeatCount:
+		if node.Negated {
+			mergedFlag.Count = 0
+		}
+
+		mergedFlag.Values = literals
+		mergedFlag.Pairs = node.Pairs
+	case RepeatAppend:
+		mergedFlag.Values = append(mergedFlag.Values, literals...)
+		mergedFlag.Pairs = append(mergedFlag.Pairs, node.Pairs...)
+	case RepeatLastWins:
+		mergedFlag.Values = literals
+		mergedFlag.Pairs = node.Pairs
+	case RepeatError:
+		if mergedFlag.Count > 1 {
+			p.addError(&ParserError{
+				Pos:           Position{Column: int(pos)},
+				Msg:           p.message(ErrRepeatedFlag, node.Name),
+				Code:          ErrRepeatedFlag,
+				Node:          node,
+				CommandConfig: cCfg,
+				FlagConfig:    flagConfigRef(flCfg),
+			})
+		}
+
+		mergedFlag.Values = literals
+		mergedFlag.Pairs = node.Pairs
+	}
+}
+
+// flagLiterals returns the values of the given flag as given, which
+// for a KeyValue flag are the whole key/value pairs.
+func (p *Parser) flagLiterals(node *Flag) []string {
+	var literals []string
+
+	if node.Pairs != nil {
+		for _, pair := range node.Pairs {
+			literals = append(literals, pair.Key+string(p.s.cfg.AssignmentOperator)+pair.Value)
+		}
+
+		return literals
+	}
+
+	for _, value := range node.ValueList {
+		literals = append(literals, value.Literal)
+	}
+
+	return literals
+}
+
+// parsePassthrough parses every argument after the current
+// ARG_DELIMITER verbatim, regardless of flag prefixes, assignment
+// operators, or multi-value delimiters. Each argument is also given
+// to bind as a Value, if not nil.
+func (p *Parser) parsePassthrough(bind func(Value)) Node {
 	nodes := []Node{}
 
-	for ; p.tok != EOL; p.next() {
-		nodes = append(nodes, &Ident{Literal: p.lit})
+	for p.tok == ARG_DELIMITER {
+		p.next()
+
+		node := &Ident{Literal: p.scanRawArg()}
+		nodes = append(nodes, node)
+
+		if bind != nil {
+			bind(Value{Literal: node.Literal, Pos: p.rawArgEnd()})
+		}
+
+		if p.observer != nil {
+			p.observePassthrough(node, node.Literal)
+		}
 	}
 
 	if len(nodes) == 0 {
@@ -455,3 +1432,37 @@ func (p *parser) parsePassthrough() Node {
 
 	return &PassthroughArgs{Nodes: nodes}
 }
+
+// observePassthrough reports the argument just scanned via
+// scanRawArg as passed through verbatim.
+func (p *Parser) observePassthrough(node Node, lit string) {
+	value := Value{Literal: lit, Pos: p.rawArgEnd()}
+
+	p.observer.Observe(Event{Kind: EventPassthrough, Pos: value.Pos, Node: node, Value: value})
+}
+
+// rawArgEnd returns the position of the last rune of the argument
+// just scanned via scanRawArg, which is just before the current
+// ARG_DELIMITER or EOL.
+func (p *Parser) rawArgEnd() Position {
+	return Position{Column: int(p.pos) - 1}
+}
+
+// scanRawArg returns the verbatim argument beginning with the
+// current token by joining the literals of every token up to the
+// next ARG_DELIMITER or EOL, at which the parser is left.
+func (p *Parser) scanRawArg() string {
+	lit := ""
+
+	for p.tok != ARG_DELIMITER && p.tok != EOL {
+		lit += p.lit
+
+		p.next()
+	}
+
+	if p.traceOn {
+		p.tracef("scanRawArg() returning %q", lit)
+	}
+
+	return lit
+}
@@ -1,13 +1,94 @@
 package argh
 
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+)
+
+const (
+	// negatedFlagPrefix is prepended to the name of a Negatable flag
+	// to form its negated spelling, e.g. "--no-color" for "--color".
+	negatedFlagPrefix = "no-"
+)
+
+const (
+	// NegativeNumbersAsFlags always parses flag-prefixed numbers as
+	// (compound) short flags, which is the default.
+	NegativeNumbersAsFlags NegativeNumbers = iota
+
+	// NegativeNumbersAsValues parses flag-prefixed numbers such as
+	// "-42" or "-3.5" as values where a flag value is expected and as
+	// positional arguments where the command has room for them,
+	// unless short flags named with digits are configured, in which
+	// case only required flag values are parsed as numbers.
+	NegativeNumbersAsValues
+
+	// NegativeNumbersAnywhere additionally parses flag-prefixed
+	// numbers as positional arguments regardless of the number of
+	// positional arguments the command expects, unless short flags
+	// named with digits are configured.
+	NegativeNumbersAnywhere
+)
+
+// NegativeNumbers controls whether flag-prefixed numeric arguments
+// are parsed as values rather than flags, which is opt-in so that the
+// zero value keeps parsing them as flags.
+type NegativeNumbers int
+
 type ParserConfig struct {
 	Prog *CommandConfig
 
 	ScannerConfig *ScannerConfig
+
+	NegativeNumbers NegativeNumbers
+
+	// NonInterspersed applies CommandConfig.NonInterspersed to every
+	// command, which the POSIXlyCorrectFromEnv option enables when the
+	// POSIXLY_CORRECT environment variable is set.
+	NonInterspersed bool
+
+	// Strict reports positional arguments in excess of those
+	// expected by a command as errors, or as unknown commands when
+	// a command has sub-commands but expects no positional arguments.
+	Strict bool
+
+	// Logger receives the structured events of each parse at
+	// slog.LevelDebug, such as TraceScan, and details of the
+	// parser's internal state at LevelTrace. Tracing is disabled
+	// when nil.
+	Logger *slog.Logger
+
+	// Catalogue provides the text of error messages and of usage,
+	// which is DefaultCatalogue when nil.
+	Catalogue Catalogue
+
+	// Observer receives the events of each parse in the order they
+	// occur, which must be safe for concurrent use when parsing via
+	// a CompiledParser from multiple goroutines.
+	Observer Observer
+}
+
+func (pCfg *ParserConfig) catalogue() Catalogue {
+	if pCfg.Catalogue == nil {
+		return DefaultCatalogue
+	}
+
+	return pCfg.Catalogue
 }
 
 type ParserOption func(*ParserConfig)
 
+// POSIXlyCorrectFromEnv is a ParserOption enabling NonInterspersed
+// when the POSIXLY_CORRECT environment variable is set, as for GNU
+// getopt, so that parsing depends on the environment only when asked.
+func POSIXlyCorrectFromEnv(pCfg *ParserConfig) {
+	if _, ok := os.LookupEnv("POSIXLY_CORRECT"); ok {
+		pCfg.NonInterspersed = true
+	}
+}
+
 func NewParserConfig(opts ...ParserOption) *ParserConfig {
 	pCfg := &ParserConfig{}
 
@@ -32,10 +113,52 @@ func NewParserConfig(opts ...ParserOption) *ParserConfig {
 type CommandConfig struct {
 	NValue     NValue
 	ValueNames []string
-	Flags      *Flags
-	Commands   *Commands
+
+	// Arity is the range of the number of positional arguments,
+	// which overrides NValue when set.
+	Arity *Arity
+
+	// Positionals describes the positional arguments by slot, which
+	// overrides NValue and ValueNames when set. The positional
+	// arguments are bound to the slots once the command has been
+	// fully parsed, so that fixed slots may follow an Optional or
+	// Variadic slot as in "cp SRC... DST". The arguments following a
+	// StopFlag are bound too, to the first Variadic slot and those
+	// after it, as in "git checkout [tree-ish] -- paths...".
+	Positionals []PositionalConfig
+
+	Flags    *Flags
+	Commands *Commands
+
+	// NonInterspersed stops flag parsing at the first positional
+	// argument so that it and every argument after it are parsed
+	// verbatim as passthrough arguments, as for wrapper commands
+	// such as "prog run <cmd> [args...]".
+	NonInterspersed bool
+
+	// Usage is a short description of the command written by
+	// WriteUsage, which is looked up in the Catalogue so that it may
+	// be translated.
+	Usage string `json:",omitempty"`
 
 	On func(Command) error `json:"-"`
+
+	// Enter is called as soon as the name of the command has been
+	// parsed, before any of its flags, values or sub-commands, and
+	// Exit once the command and any sub-command have been parsed,
+	// just before On. Both may configure further flags and
+	// sub-commands via the given Scope.
+	Enter func(*Scope) error `json:"-"`
+	Exit  func(*Scope) error `json:"-"`
+
+	// Action is called by Run with the Invocation of the command once
+	// the whole command line has been parsed successfully. Before is
+	// called beforehand for the command and each of its ancestors from
+	// the program down, and After afterwards in reverse order for each
+	// whose Before, if any, succeeded, even when Action fails.
+	Action ActionFunc `json:"-"`
+	Before ActionFunc `json:"-"`
+	After  ActionFunc `json:"-"`
 }
 
 func (cCfg *CommandConfig) init() {
@@ -52,19 +175,122 @@ func (cCfg *CommandConfig) init() {
 	}
 }
 
-func (cCfg *CommandConfig) GetCommandConfig(name string) (CommandConfig, bool) {
-	tracef("CommandConfig.GetCommandConfig(%q)", name)
+// arity returns the range of the number of positional arguments
+// of the command.
+func (cCfg *CommandConfig) arity() Arity {
+	if cCfg.Arity != nil {
+		return *cCfg.Arity
+	}
 
-	if cCfg.Commands == nil {
-		cCfg.Commands = &Commands{Map: map[string]CommandConfig{}}
+	if len(cCfg.Positionals) > 0 {
+		return positionalsArity(cCfg.Positionals)
+	}
+
+	return cCfg.NValue.Arity()
+}
+
+// positionalsArity returns the range of the number of positional
+// arguments that may be bound to the given slots.
+func positionalsArity(slots []PositionalConfig) Arity {
+	arity := Arity{}
+
+	for _, posCfg := range slots {
+		if !posCfg.Optional {
+			arity.Min++
+		}
+
+		if posCfg.Variadic {
+			arity.Max = -1
+		} else if arity.Max >= 0 {
+			arity.Max++
+		}
+	}
+
+	return arity
+}
+
+// bindStoppedPositionals returns the names of the slots to which the
+// given numbers of positional arguments before and after the stop
+// flag are bound in order. Those before are bound to the slots before
+// the first Variadic slot and those after to the rest, as in
+// "git checkout [tree-ish] -- paths...", unless they do not fit, in
+// which case all are bound as by bindPositionals. It returns false
+// when the arguments fit the slots neither way.
+func (cCfg *CommandConfig) bindStoppedPositionals(before, after int) ([]string, bool) {
+	split := len(cCfg.Positionals)
+
+	for i, posCfg := range cCfg.Positionals {
+		if posCfg.Variadic {
+			split = i
+			break
+		}
 	}
 
+	head := &CommandConfig{Positionals: cCfg.Positionals[:split]}
+	tail := &CommandConfig{Positionals: cCfg.Positionals[split:]}
+
+	if positionalsArity(head.Positionals).fits(before) && positionalsArity(tail.Positionals).fits(after) {
+		return append(head.bindPositionals(before), tail.bindPositionals(after)...), true
+	}
+
+	if positionalsArity(cCfg.Positionals).fits(before + after) {
+		return cCfg.bindPositionals(before + after), true
+	}
+
+	return nil, false
+}
+
+// bindPositionals returns the names of the slots to which the
+// given number of positional arguments are bound in order. Required
+// slots are bound first, then Optional slots from left to right, and
+// the first Variadic slot takes whatever remains, with its values
+// named "name", "name.1", "name.2" and so on.
+func (cCfg *CommandConfig) bindPositionals(n int) []string {
+	counts := make([]int, len(cCfg.Positionals))
+	extra := n
+
+	for i, posCfg := range cCfg.Positionals {
+		if !posCfg.Optional {
+			counts[i] = 1
+			extra--
+		}
+	}
+
+	for i, posCfg := range cCfg.Positionals {
+		if posCfg.Optional && extra > 0 {
+			counts[i] = 1
+			extra--
+		}
+	}
+
+	for i, posCfg := range cCfg.Positionals {
+		if posCfg.Variadic && extra > 0 {
+			counts[i] += extra
+			extra = 0
+		}
+	}
+
+	names := []string{}
+
+	for i, posCfg := range cCfg.Positionals {
+		for j := 0; j < counts[i] && len(names) < n; j++ {
+			name := posCfg.Name
+			if j > 0 {
+				name = fmt.Sprintf("%s.%d", posCfg.Name, j)
+			}
+
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func (cCfg *CommandConfig) GetCommandConfig(name string) (CommandConfig, bool) {
 	return cCfg.Commands.Get(name)
 }
 
 func (cCfg *CommandConfig) SetCommandConfig(name string, sCfg *CommandConfig) {
-	tracef("CommandConfig.SetCommandConfig(%q, ...)", name)
-
 	if cCfg.Commands == nil {
 		cCfg.Commands = &Commands{Map: map[string]CommandConfig{}}
 	}
@@ -76,18 +302,10 @@ func (cCfg *CommandConfig) SetCommandConfig(name string, sCfg *CommandConfig) {
 }
 
 func (cCfg *CommandConfig) GetFlagConfig(name string) (FlagConfig, bool) {
-	tracef("CommandConfig.GetFlagConfig(%q)", name)
-
-	if cCfg.Flags == nil {
-		cCfg.Flags = &Flags{Map: map[string]FlagConfig{}}
-	}
-
 	return cCfg.Flags.Get(name)
 }
 
 func (cCfg *CommandConfig) SetFlagConfig(name string, flCfg *FlagConfig) {
-	tracef("CommandConfig.SetFlagConfig(%q, ...)", name)
-
 	if cCfg.Flags == nil {
 		cCfg.Flags = &Flags{Map: map[string]FlagConfig{}}
 	}
@@ -95,12 +313,134 @@ func (cCfg *CommandConfig) SetFlagConfig(name string, flCfg *FlagConfig) {
 	cCfg.Flags.Set(name, flCfg)
 }
 
+// PositionalConfig describes a positional argument slot of a
+// command.
+type PositionalConfig struct {
+	Name string
+
+	// Optional allows the slot to be left empty.
+	Optional bool
+
+	// Variadic allows the slot to take more than one value, and
+	// together with Optional to take none.
+	Variadic bool
+}
+
 type FlagConfig struct {
 	NValue     NValue
 	Persist    bool
 	ValueNames []string
 
+	// Arity is the range of the number of values, which overrides
+	// NValue when set.
+	Arity *Arity
+
+	// Negatable flags may also be given with the "no-" prefix, e.g.
+	// "--no-color", which resolves to the same config as "--color".
+	// The parsed Flag records "true" or "false" as its first value
+	// depending on which spelling was used.
+	Negatable bool
+
+	// Repeat controls how repeated occurrences of the flag are
+	// folded into the merged view of the command in which the flag
+	// is configured, which includes occurrences of persistent flags
+	// given to sub-commands.
+	Repeat RepeatMode
+
+	// Terminator is an optional argument that explicitly ends the
+	// values of the flag, e.g. ";" as with find's "-exec". Values that
+	// are not required also end at the name of a sub-command.
+	Terminator string
+
+	// AttachedValue flags take a value only when it is joined to the
+	// flag via the assignment operator, e.g. "--color=always", or
+	// directly attached to a short flag, e.g. "-calways", which makes
+	// the value optional without consuming the next argument, as with
+	// git's "--color[=<when>]". NValue still bounds the number of
+	// values, which may be given as a multi-value list.
+	AttachedValue bool
+
+	// KeyValue flags take a single argument per occurrence holding
+	// one or more "key=value" pairs as a multi-value list, e.g.
+	// "--label a=b,c=d" or "-Dfoo=bar", which are recorded as the
+	// Pairs of the Flag in order and as its Values keyed by key.
+	// Repeated occurrences are appended in the merged view unless
+	// another RepeatMode is given.
+	KeyValue bool
+
+	// MultiValueDelim overrides the multi-value delimiter of the
+	// ScannerConfig for the values of the flag, e.g. ':' for
+	// PATH-style lists, where NoMultiValueDelim disables splitting so
+	// that each value is taken verbatim, as for SQL or JSON snippets.
+	MultiValueDelim rune `json:",omitempty"`
+
+	// Usage is a short description of the flag written by
+	// WriteUsage, which is looked up in the Catalogue so that it may
+	// be translated.
+	Usage string `json:",omitempty"`
+
 	On func(Flag) error `json:"-"`
+
+	// Configure is called after On with the Scope of the command the
+	// flag was given to, through which it may configure further flags
+	// and sub-commands for the remainder of the command line, such as
+	// for a flag that loads a plugin.
+	Configure func(Flag, *Scope) error `json:"-"`
+}
+
+const (
+	// NoMultiValueDelim may be given as the MultiValueDelim of a
+	// FlagConfig to disable splitting its values into multi-value
+	// lists.
+	NoMultiValueDelim rune = -1
+)
+
+const (
+	// RepeatEach keeps every occurrence of a flag as an independent
+	// Flag node without a merged view, which is the default.
+	RepeatEach RepeatMode = iota
+
+	// RepeatCount counts occurrences, e.g. "-vvv" counts 3, where an
+	// occurrence of the negated spelling of a Negatable flag resets
+	// the count to zero.
+	RepeatCount
+
+	// RepeatAppend collects the values of all occurrences.
+	RepeatAppend
+
+	// RepeatLastWins keeps the values of the last occurrence.
+	RepeatLastWins
+
+	// RepeatError reports an error for any occurrence after the
+	// first.
+	RepeatError
+)
+
+// RepeatMode controls how repeated occurrences of a flag are folded
+// into a MergedFlag.
+type RepeatMode int
+
+// arity returns the range of the number of values of the flag.
+func (flCfg *FlagConfig) arity() Arity {
+	if flCfg.Arity != nil {
+		return *flCfg.Arity
+	}
+
+	if flCfg.KeyValue && flCfg.NValue == ZeroValue {
+		return Arity{Min: 1, Max: 1}
+	}
+
+	return flCfg.NValue.Arity()
+}
+
+// repeat returns the RepeatMode of the flag, which defaults to
+// RepeatAppend for KeyValue flags.
+func (flCfg *FlagConfig) repeat() RepeatMode {
+	if flCfg.KeyValue && flCfg.Repeat == RepeatEach {
+		return RepeatAppend
+	}
+
+	return flCfg.Repeat
 }
 
 type Flags struct {
@@ -108,33 +448,136 @@ type Flags struct {
 	Map    map[string]FlagConfig
 
 	Automatic bool
+
+	// PassthroughUnknown records flags that are not configured as
+	// UnknownFlag nodes holding the verbatim argument, including any
+	// values joined via the assignment operator, instead of reporting
+	// them as errors, such as for wrappers forwarding flags to another
+	// program. Values given as separate arguments are parsed as
+	// positional arguments. Of a compound short flag, only the
+	// remainder from the first unknown flag is passed through, e.g.
+	// "-w" of "-vw". This has no effect with Automatic.
+	PassthroughUnknown bool
+
+	// index holds every flag that may be given, including persistent
+	// flags of parent commands, as built by Compile, in which case Map
+	// and Parent are no longer consulted.
+	index           map[string]indexedFlag
+	digitShortFlags bool
+}
+
+// indexedFlag is a flag config along with the Flags in which it is
+// configured.
+type indexedFlag struct {
+	flCfg FlagConfig
+	owner *Flags
 }
 
 func (fl *Flags) Get(name string) (FlagConfig, bool) {
-	tracef("Flags.Get(%q)", name)
+	flCfg, _, ok := fl.lookup(name)
+	return flCfg, ok
+}
 
-	if fl.Map == nil {
-		fl.Map = map[string]FlagConfig{}
+// lookup is like Get, but also returns the Flags in which the flag
+// is configured, which is an ancestor for persistent flags.
+func (fl *Flags) lookup(name string) (FlagConfig, *Flags, bool) {
+	if fl == nil {
+		return FlagConfig{}, nil, false
+	}
+
+	if flCfg, owner, ok := fl.lookupConfigured(name); ok {
+		return flCfg, owner, true
+	}
+
+	if fl.Automatic {
+		return FlagConfig{}, fl, true
+	}
+
+	return FlagConfig{}, nil, false
+}
+
+// lookupConfigured is like lookup, but without accepting Automatic
+// flags, which however still shadow the persistent flags of parents.
+func (fl *Flags) lookupConfigured(name string) (FlagConfig, *Flags, bool) {
+	if fl == nil {
+		return FlagConfig{}, nil, false
+	}
+
+	if fl.index != nil {
+		entry, ok := fl.index[name]
+
+		return entry.flCfg, entry.owner, ok
 	}
 
-	flCfg, ok := fl.Map[name]
-	if !ok {
-		if fl.Automatic {
-			return FlagConfig{}, true
+	if flCfg, ok := fl.Map[name]; ok {
+		return flCfg, fl, true
+	}
+
+	if fl.Automatic || fl.Parent == nil {
+		return FlagConfig{}, nil, false
+	}
+
+	flCfg, owner, ok := fl.Parent.lookup(name)
+	if !ok || !flCfg.Persist {
+		return FlagConfig{}, nil, false
+	}
+
+	return flCfg, owner, true
+}
+
+func (fl *Flags) passthroughUnknown() bool {
+	return fl != nil && fl.PassthroughUnknown
+}
+
+// hasDigitShortFlags returns whether any short flag named with a
+// digit may be given, in which case flag-prefixed numbers such as
+// "-1" are ambiguous.
+func (fl *Flags) hasDigitShortFlags() bool {
+	if fl != nil && fl.index != nil {
+		return fl.digitShortFlags
+	}
+
+	for ch := '0'; ch <= '9'; ch++ {
+		if _, ok := fl.Get(string(ch)); ok {
+			return true
 		}
+	}
+
+	return false
+}
+
+// Names returns the sorted names of all flags that may be given,
+// including persistent flags of parent commands and the negated
+// spellings of Negatable flags, such as for use in help output or
+// shell completion.
+func (fl *Flags) Names() []string {
+	seen := map[string]bool{}
 
-		if fl.Parent != nil {
-			flCfg, ok = fl.Parent.Get(name)
-			return flCfg, ok && flCfg.Persist
+	for cur, persistOnly := fl, false; cur != nil; cur, persistOnly = cur.Parent, true {
+		for name, flCfg := range cur.Map {
+			if persistOnly && !flCfg.Persist {
+				continue
+			}
+
+			seen[name] = true
+
+			if flCfg.Negatable {
+				seen[negatedFlagPrefix+name] = true
+			}
 		}
 	}
 
-	return flCfg, ok
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
 }
 
 func (fl *Flags) Set(name string, flCfg *FlagConfig) {
-	tracef("Flags.Get(%q)", name)
-
 	if fl.Map == nil {
 		fl.Map = map[string]FlagConfig{}
 	}
@@ -147,19 +590,27 @@ type Commands struct {
 }
 
 func (cmd *Commands) Get(name string) (CommandConfig, bool) {
-	tracef("Commands.Get(%q)", name)
-
-	if cmd.Map == nil {
-		cmd.Map = map[string]CommandConfig{}
+	if cmd == nil {
+		return CommandConfig{}, false
 	}
 
 	cmdCfg, ok := cmd.Map[name]
 	return cmdCfg, ok
 }
 
-func (cmd *Commands) Set(name string, cCfg *CommandConfig) {
-	tracef("Commands.Set(%q, ...)", name)
+// Names returns the sorted names of all commands.
+func (cmd *Commands) Names() []string {
+	names := make([]string, 0, len(cmd.Map))
+	for name := range cmd.Map {
+		names = append(names, name)
+	}
 
+	sort.Strings(names)
+
+	return names
+}
+
+func (cmd *Commands) Set(name string, cCfg *CommandConfig) {
 	if cmd.Map == nil {
 		cmd.Map = map[string]CommandConfig{}
 	}
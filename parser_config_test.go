@@ -0,0 +1,167 @@
+package argh
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagsNames(t *testing.T) {
+	r := require.New(t)
+
+	parent := &Flags{
+		Map: map[string]FlagConfig{
+			"color":   {Persist: true, Negatable: true},
+			"verbose": {Persist: true},
+			"local":   {},
+		},
+	}
+
+	child := &Flags{
+		Parent: parent,
+		Map: map[string]FlagConfig{
+			"f":     {},
+			"cache": {Negatable: true},
+		},
+	}
+
+	r.Equal([]string{"cache", "color", "f", "no-cache", "no-color", "verbose"}, child.Names())
+	r.Equal([]string{"color", "local", "no-color", "verbose"}, parent.Names())
+}
+
+func TestPOSIXlyCorrectFromEnv(t *testing.T) {
+	t.Setenv("POSIXLY_CORRECT", "")
+
+	require.False(t, NewParserConfig().NonInterspersed)
+	require.True(t, NewParserConfig(POSIXlyCorrectFromEnv).NonInterspersed)
+	require.False(
+		t,
+		NewParserConfig(
+			POSIXlyCorrectFromEnv,
+			func(pCfg *ParserConfig) { pCfg.NonInterspersed = false },
+		).NonInterspersed,
+	)
+
+	require.NoError(t, os.Unsetenv("POSIXLY_CORRECT"))
+	require.False(t, NewParserConfig(POSIXlyCorrectFromEnv).NonInterspersed)
+}
+
+func TestCommandsNames(t *testing.T) {
+	cmds := &Commands{
+		Map: map[string]CommandConfig{
+			"status": {},
+			"apply":  {},
+			"start":  {},
+		},
+	}
+
+	require.Equal(t, []string{"apply", "start", "status"}, cmds.Names())
+	require.Equal(t, []string{}, (&Commands{}).Names())
+}
+
+func TestCommandConfigBindPositionals(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		pos  []PositionalConfig
+		lits []string
+		exp  map[string]string
+	}{
+		{
+			name: "variadic then fixed",
+			pos:  []PositionalConfig{{Name: "src", Variadic: true}, {Name: "dst"}},
+			lits: []string{"a", "b", "c"},
+			exp:  map[string]string{"src": "a", "src.1": "b", "dst": "c"},
+		},
+		{
+			name: "optional then fixed without optional",
+			pos:  []PositionalConfig{{Name: "tree-ish", Optional: true}, {Name: "path"}},
+			lits: []string{"a"},
+			exp:  map[string]string{"path": "a"},
+		},
+		{
+			name: "optional then fixed with optional",
+			pos:  []PositionalConfig{{Name: "tree-ish", Optional: true}, {Name: "path"}},
+			lits: []string{"a", "b"},
+			exp:  map[string]string{"tree-ish": "a", "path": "b"},
+		},
+		{
+			name: "optional variadic before optional",
+			pos: []PositionalConfig{
+				{Name: "args", Optional: true, Variadic: true},
+				{Name: "last", Optional: true},
+			},
+			lits: []string{"a", "b", "c"},
+			exp:  map[string]string{"args": "a", "args.1": "b", "last": "c"},
+		},
+		{
+			name: "optional variadic left empty",
+			pos:  []PositionalConfig{{Name: "args", Optional: true, Variadic: true}, {Name: "dst"}},
+			lits: []string{"a"},
+			exp:  map[string]string{"dst": "a"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cCfg := &CommandConfig{Positionals: tc.pos}
+
+			values := map[string]string{}
+
+			for i, name := range cCfg.bindPositionals(len(tc.lits)) {
+				values[name] = tc.lits[i]
+			}
+
+			require.Equal(t, tc.exp, values)
+		})
+	}
+}
+
+func TestCommandConfigBindStoppedPositionals(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		pos    []PositionalConfig
+		before int
+		after  int
+		exp    []string
+		expOK  bool
+	}{
+		{
+			name:   "split at variadic",
+			pos:    []PositionalConfig{{Name: "tree-ish", Optional: true}, {Name: "paths", Variadic: true}},
+			before: 1,
+			after:  2,
+			exp:    []string{"tree-ish", "paths", "paths.1"},
+			expOK:  true,
+		},
+		{
+			name:   "all after stop flag",
+			pos:    []PositionalConfig{{Name: "src", Variadic: true}, {Name: "dst"}},
+			before: 0,
+			after:  3,
+			exp:    []string{"src", "src.1", "dst"},
+			expOK:  true,
+		},
+		{
+			name:   "not split when too many before",
+			pos:    []PositionalConfig{{Name: "src", Variadic: true}, {Name: "dst"}},
+			before: 1,
+			after:  1,
+			exp:    []string{"src", "dst"},
+			expOK:  true,
+		},
+		{
+			name:   "too many after without variadic",
+			pos:    []PositionalConfig{{Name: "file"}},
+			before: 1,
+			after:  1,
+			expOK:  false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cCfg := &CommandConfig{Positionals: tc.pos}
+
+			names, ok := cCfg.bindStoppedPositionals(tc.before, tc.after)
+			require.Equal(t, tc.expOK, ok)
+			require.Equal(t, tc.exp, names)
+		})
+	}
+}
@@ -6,12 +6,121 @@ import (
 	"sort"
 )
 
-// ParserError is largely borrowed from go/scanner.Error
+// ErrorCode classifies a ParserError. The values of the codes are
+// stable, with new codes only ever added to the end. Each code is
+// itself an error so that the kind of a ParserError, including within
+// a ParserErrorList, may be checked with errors.Is, e.g.:
+//
+//	errors.Is(err, argh.ErrUnknownFlag)
+type ErrorCode int
+
+const (
+	// ErrUnknownFlag is reported for a flag that is not configured.
+	ErrUnknownFlag ErrorCode = iota + 1
+
+	// ErrUnknownCommand is reported in Strict mode for a positional
+	// argument given to a command that only expects sub-commands.
+	ErrUnknownCommand
+
+	// ErrUnexpectedArgument is reported in Strict mode for a
+	// positional argument in excess of those expected by a command.
+	ErrUnexpectedArgument
+
+	// ErrMissingValue is reported for a flag given fewer values than
+	// its minimum arity.
+	ErrMissingValue
+
+	// ErrMissingPositional is reported for a command given fewer
+	// positional arguments than its minimum arity.
+	ErrMissingPositional
+
+	// ErrBareAssignment is reported for an assignment operator that
+	// does not follow a flag.
+	ErrBareAssignment
+
+	// ErrInvalidKeyValue is reported for a malformed key=value pair
+	// given to a KeyValue flag.
+	ErrInvalidKeyValue
+
+	// ErrRepeatedFlag is reported for a RepeatError flag given more
+	// than once.
+	ErrRepeatedFlag
+
+	// ErrHandler is returned for an error returned by a flag or
+	// command config handler, which is wrapped as the Err of the
+	// ParserError.
+	ErrHandler
+
+	// ErrNoAction is returned by Run when the invoked command has no
+	// Action.
+	ErrNoAction
+
+	// ErrAction is returned by Run for an error returned by the Action
+	// of the invoked command or the Before or After hooks along its
+	// path, which is wrapped as the Err of the ParserError.
+	ErrAction
+)
+
+var errorCodeDescriptions = map[ErrorCode]string{
+	ErrUnknownFlag:        "unknown flag",
+	ErrUnknownCommand:     "unknown command",
+	ErrUnexpectedArgument: "unexpected argument",
+	ErrMissingValue:       "missing value",
+	ErrMissingPositional:  "missing positional argument",
+	ErrBareAssignment:     "bare assignment",
+	ErrInvalidKeyValue:    "invalid key=value pair",
+	ErrRepeatedFlag:       "repeated flag",
+	ErrHandler:            "handler error",
+	ErrNoAction:           "no action",
+	ErrAction:             "action error",
+}
+
+func (c ErrorCode) Error() string {
+	if desc, ok := errorCodeDescriptions[c]; ok {
+		return desc
+	}
+
+	return fmt.Sprintf("ErrorCode(%[1]d)", int(c))
+}
+
+// ParserError is largely borrowed from go/scanner.Error, along with
+// the Code classifying it and, where known, the offending Node and
+// the configs in effect for it.
 type ParserError struct {
-	Pos Position
-	Msg string
+	Pos  Position
+	Msg  string
+	Code ErrorCode
+
+	// Node is the offending node, such as the *Flag given too few
+	// values or the *Ident of an unexpected argument.
+	Node Node
+
+	// Path is the names of the commands from the program to the one
+	// being parsed or run, e.g. ["prog", "sub"].
+	Path []string
+
+	// CommandConfig is the config of the command being parsed, and
+	// FlagConfig that of the offending flag, if configured.
+	CommandConfig *CommandConfig
+	FlagConfig    *FlagConfig
+
+	// Err is the underlying error, such as one returned by a
+	// handler.
+	Err error
 }
 
+// FlagError is a ParserError about a flag.
+//
+// Deprecated: Use ParserError, whose Code identifies the kind of
+// error.
+type FlagError = ParserError
+
+// CommandError is a ParserError about a command.
+//
+// Deprecated: Use ParserError, whose Code identifies the kind of
+// error.
+type CommandError = ParserError
+
 func (e ParserError) Error() string {
 	if e.Pos.IsValid() {
 		return e.Pos.String() + ":" + e.Msg
@@ -20,6 +129,20 @@ func (e ParserError) Error() string {
 	return e.Msg
 }
 
+// Is returns whether the target is the Code of the ParserError or
+// Err, which every ParserError is.
+func (e ParserError) Is(target error) bool {
+	if code, ok := target.(ErrorCode); ok {
+		return e.Code != 0 && e.Code == code
+	}
+
+	return target == Err
+}
+
+func (e ParserError) Unwrap() error {
+	return e.Err
+}
+
 // ParserErrorList is largely borrowed from go/scanner.ErrorList
 type ParserErrorList []*ParserError
 
@@ -65,6 +188,18 @@ func (el ParserErrorList) Err() error {
 	return el
 }
 
+// Unwrap returns each ParserError in the list so that errors.Is and
+// errors.As may be used to find a particular kind of error.
+func (el ParserErrorList) Unwrap() []error {
+	ret := make([]error, len(el))
+
+	for i, e := range el {
+		ret[i] = e
+	}
+
+	return ret
+}
+
 func (el ParserErrorList) Is(other error) bool {
 	if _, ok := other.(ParserErrorList); ok {
 		return el.Error() == other.Error()
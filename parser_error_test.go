@@ -0,0 +1,147 @@
+package argh_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+func TestParserErrorCodes(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	newCfg := func() *argh.ParserConfig {
+		pCfg := argh.NewParserConfig()
+		pCfg.Strict = true
+
+		pCfg.Prog.SetFlagConfig("n", &argh.FlagConfig{NValue: 1})
+		pCfg.Prog.SetFlagConfig("D", &argh.FlagConfig{KeyValue: true})
+		pCfg.Prog.SetFlagConfig("once", &argh.FlagConfig{Repeat: argh.RepeatError})
+		pCfg.Prog.SetFlagConfig("boom", &argh.FlagConfig{
+			On: func(argh.Flag) error { return errBoom },
+		})
+
+		pCfg.Prog.SetCommandConfig("cp", &argh.CommandConfig{NValue: 2})
+		pCfg.Prog.SetCommandConfig("rm", &argh.CommandConfig{
+			On: func(argh.Command) error { return errBoom },
+		})
+
+		return pCfg
+	}
+
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		expCode  argh.ErrorCode
+		expNode  argh.Node
+		expFlag  bool
+		expInner error
+	}{
+		{
+			name:    "unknown flag",
+			args:    []string{"prog", "--nope"},
+			expCode: argh.ErrUnknownFlag,
+			expNode: &argh.Flag{Name: "nope"},
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"prog", "mv"},
+			expCode: argh.ErrUnknownCommand,
+			expNode: &argh.Ident{Literal: "mv"},
+		},
+		{
+			name:    "unexpected argument",
+			args:    []string{"prog", "cp", "a", "b", "c"},
+			expCode: argh.ErrUnexpectedArgument,
+			expNode: &argh.Ident{Literal: "c"},
+		},
+		{
+			name:    "missing value",
+			args:    []string{"prog", "-n"},
+			expCode: argh.ErrMissingValue,
+			expFlag: true,
+		},
+		{
+			name:    "missing positional",
+			args:    []string{"prog", "cp", "a"},
+			expCode: argh.ErrMissingPositional,
+		},
+		{
+			name:    "bare assignment",
+			args:    []string{"prog", "="},
+			expCode: argh.ErrBareAssignment,
+			expNode: &argh.Assign{},
+		},
+		{
+			name:    "invalid key=value pair",
+			args:    []string{"prog", "-D", "nope"},
+			expCode: argh.ErrInvalidKeyValue,
+			expFlag: true,
+		},
+		{
+			name:    "repeated flag",
+			args:    []string{"prog", "--once", "--once"},
+			expCode: argh.ErrRepeatedFlag,
+			expFlag: true,
+		},
+		{
+			name:     "flag handler",
+			args:     []string{"prog", "--boom"},
+			expCode:  argh.ErrHandler,
+			expFlag:  true,
+			expInner: errBoom,
+		},
+		{
+			name:     "command handler",
+			args:     []string{"prog", "rm"},
+			expCode:  argh.ErrHandler,
+			expInner: errBoom,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			_, err := argh.ParseArgs(tc.args, newCfg())
+			r.Error(err)
+
+			r.ErrorIs(err, tc.expCode)
+			r.ErrorIs(err, argh.Err)
+
+			for _, code := range []argh.ErrorCode{argh.ErrUnknownFlag, argh.ErrHandler, argh.ErrMissingValue} {
+				if code != tc.expCode {
+					r.NotErrorIs(err, code)
+				}
+			}
+
+			pErr := &argh.ParserError{}
+			r.ErrorAs(err, &pErr)
+			r.Equal(tc.expCode, pErr.Code)
+			r.True(pErr.Pos.IsValid())
+			r.NotNil(pErr.CommandConfig)
+			r.Equal(tc.expFlag, pErr.FlagConfig != nil)
+
+			if tc.expNode != nil {
+				r.Equal(tc.expNode, pErr.Node)
+			} else {
+				r.NotNil(pErr.Node)
+			}
+
+			if tc.expInner != nil {
+				r.ErrorIs(err, tc.expInner)
+				r.Equal(tc.expInner, errors.Unwrap(pErr))
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("unknown flag", argh.ErrUnknownFlag.Error())
+	r.Equal("ErrorCode(0)", argh.ErrorCode(0).Error())
+
+	// NOTE: the values of codes are stable across releases.
+	r.Equal(1, int(argh.ErrUnknownFlag))
+	r.Equal(9, int(argh.ErrHandler))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/urfave/argh"
 )
 
@@ -79,6 +80,9 @@ func TestParser(t *testing.T) {
 								&argh.ArgDelimiter{},
 								&argh.Ident{Literal: "mario"},
 							},
+							ValueList: []argh.Value{
+								{Name: "name", Literal: "mario", Pos: argh.Position{Column: 27}},
+							},
 						},
 					},
 				},
@@ -99,6 +103,9 @@ func TestParser(t *testing.T) {
 							Nodes: []argh.Node{
 								&argh.Ident{Literal: "mario"},
 							},
+							ValueList: []argh.Value{
+								{Name: "name", Literal: "mario", Pos: argh.Position{Column: 27}},
+							},
 						},
 					},
 				},
@@ -164,6 +171,9 @@ func TestParser(t *testing.T) {
 									},
 								},
 							},
+							ValueList: []argh.Value{
+								{Name: "name", Literal: "mario", Pos: argh.Position{Column: 22}},
+							},
 						},
 					},
 				},
@@ -184,6 +194,9 @@ func TestParser(t *testing.T) {
 								&argh.Flag{Name: "a"},
 								&argh.Flag{Name: "t"},
 							},
+							ValueList: []argh.Value{
+								{Name: "name", Literal: "mario", Pos: argh.Position{Column: 22}},
+							},
 						},
 					},
 				},
@@ -217,6 +230,9 @@ func TestParser(t *testing.T) {
 						&argh.ArgDelimiter{},
 						&argh.Ident{Literal: "excel"},
 					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "excel", Pos: argh.Position{Column: 12}},
+					},
 				},
 			},
 			expAST: []argh.Node{
@@ -226,6 +242,9 @@ func TestParser(t *testing.T) {
 					Nodes: []argh.Node{
 						&argh.Ident{Literal: "excel"},
 					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "excel", Pos: argh.Position{Column: 12}},
+					},
 				},
 			},
 		},
@@ -258,6 +277,12 @@ func TestParser(t *testing.T) {
 						&argh.ArgDelimiter{},
 						&argh.Ident{Literal: "feral"},
 					},
+					ValueList: []argh.Value{
+						{Name: "word", Literal: "excel", Pos: argh.Position{Column: 12}},
+						{Name: "word.1", Literal: "wildly", Pos: argh.Position{Column: 19}, Group: 1},
+						{Name: "word.2", Literal: "when", Pos: argh.Position{Column: 24}, Group: 2},
+						{Name: "word.3", Literal: "feral", Pos: argh.Position{Column: 30}, Group: 3},
+					},
 				},
 			},
 			expAST: []argh.Node{
@@ -275,6 +300,12 @@ func TestParser(t *testing.T) {
 						&argh.Ident{Literal: "when"},
 						&argh.Ident{Literal: "feral"},
 					},
+					ValueList: []argh.Value{
+						{Name: "word", Literal: "excel", Pos: argh.Position{Column: 12}},
+						{Name: "word.1", Literal: "wildly", Pos: argh.Position{Column: 19}, Group: 1},
+						{Name: "word.2", Literal: "when", Pos: argh.Position{Column: 24}, Group: 2},
+						{Name: "word.3", Literal: "feral", Pos: argh.Position{Column: 30}, Group: 3},
+					},
 				},
 			},
 		},
@@ -320,6 +351,11 @@ func TestParser(t *testing.T) {
 								},
 								&argh.ArgDelimiter{},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "whales", Pos: argh.Position{Column: 20}, Source: argh.ValueFromAssign},
+								{Name: "1", Literal: "majesticness", Pos: argh.Position{Column: 33}, Source: argh.ValueFromAssign},
+								{Name: "2", Literal: "waters", Pos: argh.Position{Column: 40}, Source: argh.ValueFromAssign},
+							},
 						},
 						&argh.Flag{
 							Name: "a",
@@ -339,6 +375,11 @@ func TestParser(t *testing.T) {
 								&argh.ArgDelimiter{},
 								&argh.Ident{Literal: "probably ducks"},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "sparkling", Pos: argh.Position{Column: 53}},
+								{Name: "1", Literal: "lens flares", Pos: argh.Position{Column: 65}},
+								{Name: "2", Literal: "probably ducks", Pos: argh.Position{Column: 80}, Group: 1},
+							},
 						},
 					},
 				},
@@ -364,6 +405,11 @@ func TestParser(t *testing.T) {
 									},
 								},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "whales", Pos: argh.Position{Column: 20}, Source: argh.ValueFromAssign},
+								{Name: "1", Literal: "majesticness", Pos: argh.Position{Column: 33}, Source: argh.ValueFromAssign},
+								{Name: "2", Literal: "waters", Pos: argh.Position{Column: 40}, Source: argh.ValueFromAssign},
+							},
 						},
 						&argh.Flag{
 							Name: "a",
@@ -381,6 +427,11 @@ func TestParser(t *testing.T) {
 								},
 								&argh.Ident{Literal: "probably ducks"},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "sparkling", Pos: argh.Position{Column: 53}},
+								{Name: "1", Literal: "lens flares", Pos: argh.Position{Column: 65}},
+								{Name: "2", Literal: "probably ducks", Pos: argh.Position{Column: 80}, Group: 1},
+							},
 						},
 					},
 				},
@@ -464,6 +515,9 @@ func TestParser(t *testing.T) {
 								&argh.ArgDelimiter{},
 								&argh.Ident{Literal: "soon"},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "soon", Pos: argh.Position{Column: 27}},
+							},
 						},
 						&argh.ArgDelimiter{},
 						&argh.Flag{Name: "super-hot-right-now"},
@@ -480,6 +534,11 @@ func TestParser(t *testing.T) {
 								&argh.Ident{Literal: "hot"},
 								&argh.ArgDelimiter{},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "square", Pos: argh.Position{Column: 62}},
+								{Name: "1", Literal: "shaped", Pos: argh.Position{Column: 69}, Group: 1},
+								{Name: "2", Literal: "hot", Pos: argh.Position{Column: 73}, Group: 2},
+							},
 						},
 						&argh.Flag{Name: "please"},
 					},
@@ -496,6 +555,9 @@ func TestParser(t *testing.T) {
 							Nodes: []argh.Node{
 								&argh.Ident{Literal: "soon"},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "soon", Pos: argh.Position{Column: 27}},
+							},
 						},
 						&argh.Flag{Name: "super-hot-right-now"},
 						&argh.Flag{
@@ -506,6 +568,11 @@ func TestParser(t *testing.T) {
 								&argh.Ident{Literal: "shaped"},
 								&argh.Ident{Literal: "hot"},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "square", Pos: argh.Position{Column: 62}},
+								{Name: "1", Literal: "shaped", Pos: argh.Position{Column: 69}, Group: 1},
+								{Name: "2", Literal: "hot", Pos: argh.Position{Column: 73}, Group: 2},
+							},
 						},
 						&argh.Flag{Name: "please"},
 					},
@@ -641,6 +708,9 @@ func TestParser(t *testing.T) {
 								&argh.ArgDelimiter{},
 								&argh.Ident{Literal: "1312"},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "1312", Pos: argh.Position{Column: 22}},
+							},
 						},
 						&argh.ArgDelimiter{},
 						&argh.CompoundShortFlag{
@@ -665,6 +735,9 @@ func TestParser(t *testing.T) {
 							Nodes: []argh.Node{
 								&argh.Ident{Literal: "1312"},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "1312", Pos: argh.Position{Column: 22}},
+							},
 						},
 						&argh.Flag{Name: "l"},
 						&argh.Flag{Name: "o"},
@@ -802,6 +875,11 @@ func TestParser(t *testing.T) {
 										&argh.Ident{Literal: "love"},
 										&argh.ArgDelimiter{},
 									},
+									ValueList: []argh.Value{
+										{Name: "0", Literal: "sauce", Pos: argh.Position{Column: 18}},
+										{Name: "1", Literal: "heat", Pos: argh.Position{Column: 23}, Group: 1},
+										{Name: "2", Literal: "love", Pos: argh.Position{Column: 28}, Group: 2},
+									},
 								},
 							},
 						},
@@ -819,6 +897,9 @@ func TestParser(t *testing.T) {
 										&argh.ArgDelimiter{},
 										&argh.Ident{Literal: "over9000"},
 									},
+									ValueList: []argh.Value{
+										{Name: "level", Literal: "over9000", Pos: argh.Position{Column: 43}},
+									},
 								},
 							},
 						},
@@ -844,6 +925,11 @@ func TestParser(t *testing.T) {
 								&argh.Ident{Literal: "heat"},
 								&argh.Ident{Literal: "love"},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "sauce", Pos: argh.Position{Column: 18}},
+								{Name: "1", Literal: "heat", Pos: argh.Position{Column: 23}, Group: 1},
+								{Name: "2", Literal: "love", Pos: argh.Position{Column: 28}, Group: 2},
+							},
 						},
 						&argh.Flag{Name: "a"},
 						&argh.Flag{Name: "l"},
@@ -856,6 +942,9 @@ func TestParser(t *testing.T) {
 							Nodes: []argh.Node{
 								&argh.Ident{Literal: "over9000"},
 							},
+							ValueList: []argh.Value{
+								{Name: "level", Literal: "over9000", Pos: argh.Position{Column: 43}},
+							},
 						},
 					},
 				},
@@ -923,6 +1012,9 @@ func TestParser(t *testing.T) {
 														&argh.ArgDelimiter{},
 														&argh.Ident{Literal: "hugs"},
 													},
+													ValueList: []argh.Value{
+														{Name: "0", Literal: "hugs", Pos: argh.Position{Column: 42}},
+													},
 												},
 											},
 										},
@@ -953,6 +1045,9 @@ func TestParser(t *testing.T) {
 											Nodes: []argh.Node{
 												&argh.Ident{Literal: "hugs"},
 											},
+											ValueList: []argh.Value{
+												{Name: "0", Literal: "hugs", Pos: argh.Position{Column: 42}},
+											},
 										},
 									},
 								},
@@ -1011,6 +1106,9 @@ func TestParser(t *testing.T) {
 										&argh.Assign{},
 										&argh.Ident{Literal: "golf"},
 									},
+									ValueList: []argh.Value{
+										{Name: "0", Literal: "golf", Pos: argh.Position{Column: 16}, Source: argh.ValueFromAssign},
+									},
 								},
 							},
 						},
@@ -1031,8 +1129,14 @@ func TestParser(t *testing.T) {
 										&argh.Assign{},
 										&argh.Ident{Literal: "-2"},
 									},
+									ValueList: []argh.Value{
+										{Name: "0", Literal: "-2", Pos: argh.Position{Column: 53}, Source: argh.ValueFromAssign},
+									},
 								},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "bonk", Pos: argh.Position{Column: 37}},
+							},
 						},
 					},
 				},
@@ -1079,6 +1183,9 @@ func TestParser(t *testing.T) {
 								&argh.Assign{},
 								&argh.Ident{Literal: "ppy"},
 							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "ppy", Pos: argh.Position{Column: 19}, Source: argh.ValueFromAssign},
+							},
 						},
 						&argh.ArgDelimiter{},
 						&argh.Command{Name: "hats"},
@@ -1113,60 +1220,1625 @@ func TestParser(t *testing.T) {
 				},
 			},
 		},
-	} {
-		if tc.expPT != nil {
-			t.Run(tc.name+" parse tree", func(ct *testing.T) {
-				if tc.skip {
-					ct.SkipNow()
-					return
-				}
-
-				pCfg := tc.cfg
-				if pCfg == nil {
-					pCfg = argh.NewParserConfig()
-				}
-
-				pt, err := argh.ParseArgs(tc.args, pCfg)
-				if err != nil || tc.expErr != nil {
-					if !assert.ErrorIs(ct, err, tc.expErr) {
-						spew.Dump(err, tc.expErr)
-						spew.Dump(pt)
-					}
-					return
-				}
-
-				if !assert.Equal(ct, tc.expPT, pt.Nodes) {
-					spew.Dump(pt)
-				}
-			})
-		}
-
-		if tc.expAST != nil {
-			t.Run(tc.name+" ast", func(ct *testing.T) {
-				if tc.skip {
-					ct.SkipNow()
-					return
-				}
-
-				pCfg := tc.cfg
-				if pCfg == nil {
-					pCfg = argh.NewParserConfig()
-				}
-
-				pt, err := argh.ParseArgs(tc.args, pCfg)
-				if err != nil || tc.expErr != nil {
-					if !assert.ErrorIs(ct, err, tc.expErr) {
-						spew.Dump(pt)
-					}
-					return
-				}
-
-				ast := argh.ToAST(pt.Nodes)
-
-				if !assert.Equal(ct, tc.expAST, ast) {
-					spew.Dump(ast)
-				}
-			})
-		}
-	}
+		{
+			name: "negatable flags",
+			args: []string{"pizzas", "--no-cheese", "--sauce", "--cheese", "--no-sauce"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"cheese": {Negatable: true, On: traceOnFlag},
+							"sauce":  {Negatable: true, ValueNames: []string{"wanted"}, On: traceOnFlag},
+						},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "pizzas",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:    "cheese",
+							Negated: true,
+							Values:  map[string]string{"0": "false"},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "false", Pos: argh.Position{Column: 18}, Source: argh.ValueFromFlag},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "sauce",
+							Values: map[string]string{"wanted": "true"},
+							ValueList: []argh.Value{
+								{Name: "wanted", Literal: "true", Pos: argh.Position{Column: 26}, Source: argh.ValueFromFlag},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "cheese",
+							Values: map[string]string{"0": "true"},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "true", Pos: argh.Position{Column: 35}, Source: argh.ValueFromFlag},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:    "sauce",
+							Negated: true,
+							Values:  map[string]string{"wanted": "false"},
+							ValueList: []argh.Value{
+								{Name: "wanted", Literal: "false", Pos: argh.Position{Column: 46}, Source: argh.ValueFromFlag},
+							},
+						},
+					},
+				},
+			},
+			expAST: []argh.Node{
+				&argh.Command{
+					Name: "pizzas",
+					Nodes: []argh.Node{
+						&argh.Flag{
+							Name:    "cheese",
+							Negated: true,
+							Values:  map[string]string{"0": "false"},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "false", Pos: argh.Position{Column: 18}, Source: argh.ValueFromFlag},
+							},
+						},
+						&argh.Flag{
+							Name:   "sauce",
+							Values: map[string]string{"wanted": "true"},
+							ValueList: []argh.Value{
+								{Name: "wanted", Literal: "true", Pos: argh.Position{Column: 26}, Source: argh.ValueFromFlag},
+							},
+						},
+						&argh.Flag{
+							Name:   "cheese",
+							Values: map[string]string{"0": "true"},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "true", Pos: argh.Position{Column: 35}, Source: argh.ValueFromFlag},
+							},
+						},
+						&argh.Flag{
+							Name:    "sauce",
+							Negated: true,
+							Values:  map[string]string{"wanted": "false"},
+							ValueList: []argh.Value{
+								{Name: "wanted", Literal: "false", Pos: argh.Position{Column: 46}, Source: argh.ValueFromFlag},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "attached optional values",
+			args: []string{"git", "--color", "always", "--color=never", "-vcauto", "-c"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: argh.ZeroOrMoreValue,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"color": {NValue: 1, AttachedValue: true, On: traceOnFlag},
+							"c":     {NValue: 1, AttachedValue: true, On: traceOnFlag},
+							"v":     {On: traceOnFlag},
+						},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name:   "git",
+					Values: map[string]string{"0": "always"},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{Name: "color"},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "always"},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "color",
+							Values: map[string]string{"0": "never"},
+							Nodes: []argh.Node{
+								&argh.Assign{},
+								&argh.Ident{Literal: "never"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "never", Pos: argh.Position{Column: 32}, Source: argh.ValueFromAssign},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.CompoundShortFlag{
+							Nodes: []argh.Node{
+								&argh.Flag{Name: "v"},
+								&argh.Flag{
+									Name:   "c",
+									Values: map[string]string{"0": "auto"},
+									Nodes: []argh.Node{
+										&argh.Ident{Literal: "auto"},
+									},
+									ValueList: []argh.Value{
+										{Name: "0", Literal: "auto", Pos: argh.Position{Column: 40}, Source: argh.ValueFromAttached},
+									},
+								},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{Name: "c"},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "always", Pos: argh.Position{Column: 18}},
+					},
+				},
+			},
+			expAST: []argh.Node{
+				&argh.Command{
+					Name:   "git",
+					Values: map[string]string{"0": "always"},
+					Nodes: []argh.Node{
+						&argh.Flag{Name: "color"},
+						&argh.Ident{Literal: "always"},
+						&argh.Flag{
+							Name:   "color",
+							Values: map[string]string{"0": "never"},
+							Nodes: []argh.Node{
+								&argh.Assign{},
+								&argh.Ident{Literal: "never"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "never", Pos: argh.Position{Column: 32}, Source: argh.ValueFromAssign},
+							},
+						},
+						&argh.Flag{Name: "v"},
+						&argh.Flag{
+							Name:   "c",
+							Values: map[string]string{"0": "auto"},
+							Nodes: []argh.Node{
+								&argh.Ident{Literal: "auto"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "auto", Pos: argh.Position{Column: 40}, Source: argh.ValueFromAttached},
+							},
+						},
+						&argh.Flag{Name: "c"},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "always", Pos: argh.Position{Column: 18}},
+					},
+				},
+			},
+		},
+		{
+			name: "attached short flag values",
+			args: []string{"tar", "-xvfarchive.tar", "-n5", "-C", "dir"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"x": {On: traceOnFlag},
+							"v": {On: traceOnFlag},
+							"f": {NValue: 1, On: traceOnFlag},
+							"n": {NValue: 1, On: traceOnFlag},
+							"C": {NValue: 1, On: traceOnFlag},
+						},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "tar",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.CompoundShortFlag{
+							Nodes: []argh.Node{
+								&argh.Flag{Name: "x"},
+								&argh.Flag{Name: "v"},
+								&argh.Flag{
+									Name:   "f",
+									Values: map[string]string{"0": "archive.tar"},
+									Nodes: []argh.Node{
+										&argh.Ident{Literal: "archive.tar"},
+									},
+									ValueList: []argh.Value{
+										{Name: "0", Literal: "archive.tar", Pos: argh.Position{Column: 19}, Source: argh.ValueFromAttached},
+									},
+								},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.CompoundShortFlag{
+							Nodes: []argh.Node{
+								&argh.Flag{
+									Name:   "n",
+									Values: map[string]string{"0": "5"},
+									Nodes: []argh.Node{
+										&argh.Ident{Literal: "5"},
+									},
+									ValueList: []argh.Value{
+										{Name: "0", Literal: "5", Pos: argh.Position{Column: 23}, Source: argh.ValueFromAttached},
+									},
+								},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "C",
+							Values: map[string]string{"0": "dir"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "dir"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "dir", Pos: argh.Position{Column: 30}},
+							},
+						},
+					},
+				},
+			},
+			expAST: []argh.Node{
+				&argh.Command{
+					Name: "tar",
+					Nodes: []argh.Node{
+						&argh.Flag{Name: "x"},
+						&argh.Flag{Name: "v"},
+						&argh.Flag{
+							Name:   "f",
+							Values: map[string]string{"0": "archive.tar"},
+							Nodes: []argh.Node{
+								&argh.Ident{Literal: "archive.tar"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "archive.tar", Pos: argh.Position{Column: 19}, Source: argh.ValueFromAttached},
+							},
+						},
+						&argh.Flag{
+							Name:   "n",
+							Values: map[string]string{"0": "5"},
+							Nodes: []argh.Node{
+								&argh.Ident{Literal: "5"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "5", Pos: argh.Position{Column: 23}, Source: argh.ValueFromAttached},
+							},
+						},
+						&argh.Flag{
+							Name:   "C",
+							Values: map[string]string{"0": "dir"},
+							Nodes: []argh.Node{
+								&argh.Ident{Literal: "dir"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "dir", Pos: argh.Position{Column: 30}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "attached short flag value with assignment operator",
+			args: []string{"prog", "-vofoo=bar"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"v": {},
+							"o": {NValue: 1},
+						},
+					},
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "prog",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.CompoundShortFlag{
+							Nodes: []argh.Node{
+								&argh.Flag{Name: "v"},
+								&argh.Flag{
+									Name:   "o",
+									Values: map[string]string{"0": "foo=bar"},
+									Nodes: []argh.Node{
+										&argh.Ident{Literal: "foo=bar"},
+									},
+									ValueList: []argh.Value{
+										{Name: "0", Literal: "foo=bar", Pos: argh.Position{Column: 15}, Source: argh.ValueFromAttached},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "negated spelling configured as flag",
+			args: []string{"prog", "--no-foo", "bar", "--no-baz"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Automatic: true,
+						Map: map[string]argh.FlagConfig{
+							"foo":    {Negatable: true},
+							"no-foo": {NValue: 1},
+							"baz":    {Negatable: true},
+						},
+					},
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "prog",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "no-foo",
+							Values: map[string]string{"0": "bar"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "bar"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "bar", Pos: argh.Position{Column: 17}},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:    "baz",
+							Negated: true,
+							Values:  map[string]string{"0": "false"},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "false", Pos: argh.Position{Column: 26}, Source: argh.ValueFromFlag},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "negative numbers as flags by default",
+			args: []string{"calc", "--offset", "-42", "-1"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: argh.ZeroOrMoreValue,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"offset": {NValue: argh.ZeroOrMoreValue},
+							"1":      {},
+							"2":      {},
+							"4":      {},
+						},
+					},
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "calc",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:  "offset",
+							Nodes: []argh.Node{&argh.ArgDelimiter{}},
+						},
+						&argh.CompoundShortFlag{
+							Nodes: []argh.Node{
+								&argh.Flag{Name: "4"},
+								&argh.Flag{Name: "2"},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{Name: "1"},
+					},
+				},
+			},
+		},
+		{
+			name: "negative numbers",
+			args: []string{"calc", "--offset", "-42", "-3.5", "--scale=-1e3", "-v"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: argh.ZeroOrMoreValue,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"offset": {NValue: 1, On: traceOnFlag},
+							"scale":  {NValue: 1, On: traceOnFlag},
+							"v":      {On: traceOnFlag},
+						},
+					},
+					On: traceOnCommand,
+				},
+				NegativeNumbers: argh.NegativeNumbersAsValues,
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name:   "calc",
+					Values: map[string]string{"0": "-3.5"},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "offset",
+							Values: map[string]string{"0": "-42"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "-42"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "-42", Pos: argh.Position{Column: 17}},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "-3.5"},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "scale",
+							Values: map[string]string{"0": "-1e3"},
+							Nodes: []argh.Node{
+								&argh.Assign{},
+								&argh.Ident{Literal: "-1e3"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "-1e3", Pos: argh.Position{Column: 35}, Source: argh.ValueFromAssign},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{Name: "v"},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "-3.5", Pos: argh.Position{Column: 22}},
+					},
+				},
+			},
+		},
+		{
+			name: "negative numbers with digit short flags",
+			args: []string{"head", "-1", "--lines", "-5", "-7"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: argh.ZeroOrMoreValue,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"1":     {On: traceOnFlag},
+							"7":     {On: traceOnFlag},
+							"lines": {NValue: 1, On: traceOnFlag},
+						},
+					},
+					On: traceOnCommand,
+				},
+				NegativeNumbers: argh.NegativeNumbersAsValues,
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "head",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{Name: "1"},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "lines",
+							Values: map[string]string{"0": "-5"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "-5"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "-5", Pos: argh.Position{Column: 18}},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{Name: "7"},
+					},
+				},
+			},
+		},
+		{
+			name: "negative numbers anywhere",
+			args: []string{"seq", "-5", "-1", "--step", "-2"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: 1,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"step": {NValue: argh.ZeroOrMoreValue, On: traceOnFlag},
+						},
+					},
+					On: traceOnCommand,
+				},
+				NegativeNumbers: argh.NegativeNumbersAnywhere,
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name:   "seq",
+					Values: map[string]string{"0": "-5"},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "-5"},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "-1"},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "step",
+							Values: map[string]string{"0": "-2"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "-2"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "-2", Pos: argh.Position{Column: 19}},
+							},
+						},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "-5", Pos: argh.Position{Column: 6}},
+					},
+				},
+			},
+		},
+		{
+			name: "stop flag with passthrough args",
+			args: []string{"kubectl", "exec", "pod", "-v", "--", "sh", "-c", "x=1,2", "-v", "", "--"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Commands: &argh.Commands{
+						Map: map[string]argh.CommandConfig{
+							"exec": {
+								NValue: 1,
+								Flags: &argh.Flags{
+									Map: map[string]argh.FlagConfig{
+										"v": {On: traceOnFlag},
+									},
+								},
+								On: traceOnCommand,
+							},
+						},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "kubectl",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Command{
+							Name:   "exec",
+							Values: map[string]string{"0": "pod"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "pod"},
+								&argh.ArgDelimiter{},
+								&argh.Flag{Name: "v"},
+								&argh.ArgDelimiter{},
+								&argh.StopFlag{},
+								&argh.ArgDelimiter{},
+								&argh.PassthroughArgs{
+									Nodes: []argh.Node{
+										&argh.Ident{Literal: "sh"},
+										&argh.Ident{Literal: "-c"},
+										&argh.Ident{Literal: "x=1,2"},
+										&argh.Ident{Literal: "-v"},
+										&argh.Ident{Literal: ""},
+										&argh.Ident{Literal: "--"},
+									},
+								},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "pod", Pos: argh.Position{Column: 16}},
+							},
+						},
+					},
+				},
+			},
+			expAST: []argh.Node{
+				&argh.Command{
+					Name: "kubectl",
+					Nodes: []argh.Node{
+						&argh.Command{
+							Name:   "exec",
+							Values: map[string]string{"0": "pod"},
+							Nodes: []argh.Node{
+								&argh.Ident{Literal: "pod"},
+								&argh.Flag{Name: "v"},
+								&argh.StopFlag{},
+								&argh.PassthroughArgs{
+									Nodes: []argh.Node{
+										&argh.Ident{Literal: "sh"},
+										&argh.Ident{Literal: "-c"},
+										&argh.Ident{Literal: "x=1,2"},
+										&argh.Ident{Literal: "-v"},
+										&argh.Ident{Literal: ""},
+										&argh.Ident{Literal: "--"},
+									},
+								},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "pod", Pos: argh.Position{Column: 16}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "trailing stop flag",
+			args: []string{"pizzas", "--"},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "pizzas",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.StopFlag{},
+					},
+				},
+			},
+			expAST: []argh.Node{
+				&argh.Command{
+					Name: "pizzas",
+					Nodes: []argh.Node{
+						&argh.StopFlag{},
+					},
+				},
+			},
+		},
+		{
+			name: "non-interspersed command",
+			args: []string{"prog", "-v", "run", "-x", "env", "FOO=bar", "-v", "--", "sub"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"v": {On: traceOnFlag},
+						},
+					},
+					Commands: &argh.Commands{
+						Map: map[string]argh.CommandConfig{
+							"run": {
+								NValue:          argh.ZeroOrMoreValue,
+								ValueNames:      []string{"cmd"},
+								NonInterspersed: true,
+								Flags: &argh.Flags{
+									Map: map[string]argh.FlagConfig{
+										"x": {On: traceOnFlag},
+									},
+								},
+								Commands: &argh.Commands{
+									Map: map[string]argh.CommandConfig{
+										"sub": {},
+									},
+								},
+								On: traceOnCommand,
+							},
+						},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "prog",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{Name: "v"},
+						&argh.ArgDelimiter{},
+						&argh.Command{
+							Name:   "run",
+							Values: map[string]string{"cmd": "env"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Flag{Name: "x"},
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "env"},
+								&argh.ArgDelimiter{},
+								&argh.PassthroughArgs{
+									Nodes: []argh.Node{
+										&argh.Ident{Literal: "FOO=bar"},
+										&argh.Ident{Literal: "-v"},
+										&argh.Ident{Literal: "--"},
+										&argh.Ident{Literal: "sub"},
+									},
+								},
+							},
+							ValueList: []argh.Value{
+								{Name: "cmd", Literal: "env", Pos: argh.Position{Column: 18}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "non-interspersed parser",
+			args: []string{"xargs", "-0", "echo", "-n", "hi"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: 1,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"0": {On: traceOnFlag},
+						},
+					},
+					On: traceOnCommand,
+				},
+				NonInterspersed: true,
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name:   "xargs",
+					Values: map[string]string{"0": "echo"},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{Name: "0"},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "echo"},
+						&argh.ArgDelimiter{},
+						&argh.PassthroughArgs{
+							Nodes: []argh.Node{
+								&argh.Ident{Literal: "-n"},
+								&argh.Ident{Literal: "hi"},
+							},
+						},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "echo", Pos: argh.Position{Column: 13}},
+					},
+				},
+			},
+		},
+		{
+			name: "unknown flag passthrough",
+			args: []string{"gowrap", "build", "-v", "--gcflags=-N,-l", "-vx", "-o", "out", "./..."},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: argh.ZeroOrMoreValue,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"v": {On: traceOnFlag},
+						},
+						PassthroughUnknown: true,
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "gowrap",
+					Values: map[string]string{
+						"0": "build",
+						"1": "out",
+						"2": "./...",
+					},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "build"},
+						&argh.ArgDelimiter{},
+						&argh.Flag{Name: "v"},
+						&argh.ArgDelimiter{},
+						&argh.UnknownFlag{Literal: "--gcflags=-N,-l"},
+						&argh.ArgDelimiter{},
+						&argh.CompoundShortFlag{
+							Nodes: []argh.Node{
+								&argh.Flag{Name: "v"},
+								&argh.UnknownFlag{Literal: "-x"},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.UnknownFlag{Literal: "-o"},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "out"},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "./..."},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "build", Pos: argh.Position{Column: 12}},
+						{Name: "1", Literal: "out", Pos: argh.Position{Column: 42}, Group: 1},
+						{Name: "2", Literal: "./...", Pos: argh.Position{Column: 48}, Group: 2},
+					},
+				},
+			},
+			expAST: []argh.Node{
+				&argh.Command{
+					Name: "gowrap",
+					Values: map[string]string{
+						"0": "build",
+						"1": "out",
+						"2": "./...",
+					},
+					Nodes: []argh.Node{
+						&argh.Ident{Literal: "build"},
+						&argh.Flag{Name: "v"},
+						&argh.UnknownFlag{Literal: "--gcflags=-N,-l"},
+						&argh.Flag{Name: "v"},
+						&argh.UnknownFlag{Literal: "-x"},
+						&argh.UnknownFlag{Literal: "-o"},
+						&argh.Ident{Literal: "out"},
+						&argh.Ident{Literal: "./..."},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "build", Pos: argh.Position{Column: 12}},
+						{Name: "1", Literal: "out", Pos: argh.Position{Column: 42}, Group: 1},
+						{Name: "2", Literal: "./...", Pos: argh.Position{Column: 48}, Group: 2},
+					},
+				},
+			},
+		},
+		{
+			name: "one or more values yield to sub-command",
+			args: []string{"prog", "--tags", "a", "b", "deploy", "now"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"tags": {NValue: argh.OneOrMoreValue, On: traceOnFlag},
+						},
+					},
+					Commands: &argh.Commands{
+						Map: map[string]argh.CommandConfig{
+							"deploy": {NValue: 1, On: traceOnCommand},
+						},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "prog",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "tags",
+							Values: map[string]string{"0": "a", "1": "b"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "a"},
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "b"},
+								&argh.ArgDelimiter{},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "a", Pos: argh.Position{Column: 13}},
+								{Name: "1", Literal: "b", Pos: argh.Position{Column: 15}, Group: 1},
+							},
+						},
+						&argh.Command{
+							Name:   "deploy",
+							Values: map[string]string{"0": "now"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "now"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "now", Pos: argh.Position{Column: 26}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "assigned value named like sub-command",
+			args: []string{"prog", "--tags=deploy", "deploy"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"tags": {NValue: argh.OneOrMoreValue, On: traceOnFlag},
+						},
+					},
+					Commands: &argh.Commands{
+						Map: map[string]argh.CommandConfig{
+							"deploy": {On: traceOnCommand},
+						},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "prog",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "tags",
+							Values: map[string]string{"0": "deploy"},
+							Nodes: []argh.Node{
+								&argh.Assign{},
+								&argh.Ident{Literal: "deploy"},
+								&argh.ArgDelimiter{},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "deploy", Pos: argh.Position{Column: 18}, Source: argh.ValueFromAssign},
+							},
+						},
+						&argh.Command{Name: "deploy"},
+					},
+				},
+			},
+		},
+		{
+			name: "too few values before sub-command",
+			args: []string{"prog", "--pair", "a", "deploy", "--tags", "deploy"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"pair": {NValue: 2},
+						},
+					},
+					Commands: &argh.Commands{
+						Map: map[string]argh.CommandConfig{
+							"deploy": {
+								Flags: &argh.Flags{
+									Map: map[string]argh.FlagConfig{
+										"tags": {NValue: argh.OneOrMoreValue},
+									},
+								},
+								Commands: &argh.Commands{
+									Map: map[string]argh.CommandConfig{
+										"deploy": {},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expErr: argh.ParserErrorList{
+				&argh.ParserError{Pos: argh.Position{Column: 11}, Msg: "flag \"pair\" expects 2 values, got 1"},
+				&argh.ParserError{Pos: argh.Position{Column: 27}, Msg: "flag \"tags\" expects at least 1 value, got 0"},
+			},
+			expPT: []argh.Node{},
+		},
+		{
+			name: "zero or more values yield to sub-command",
+			args: []string{"prog", "--tags", "deploy"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"tags": {NValue: argh.ZeroOrMoreValue, On: traceOnFlag},
+						},
+					},
+					Commands: &argh.Commands{
+						Map: map[string]argh.CommandConfig{
+							"deploy": {On: traceOnCommand},
+						},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "prog",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name: "tags",
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+							},
+						},
+						&argh.Command{Name: "deploy"},
+					},
+				},
+			},
+		},
+		{
+			name: "values ended by terminator",
+			args: []string{"prog", "--files", "a", "b", ";", "c"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: argh.ZeroOrMoreValue,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"files": {NValue: argh.OneOrMoreValue, Terminator: ";", On: traceOnFlag},
+						},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name:   "prog",
+					Values: map[string]string{"0": "c"},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "files",
+							Values: map[string]string{"0": "a", "1": "b"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "a"},
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "b"},
+								&argh.ArgDelimiter{},
+								&argh.ListTerminator{Literal: ";"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "a", Pos: argh.Position{Column: 14}},
+								{Name: "1", Literal: "b", Pos: argh.Position{Column: 16}, Group: 1},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "c"},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "c", Pos: argh.Position{Column: 20}},
+					},
+				},
+			},
+			expAST: []argh.Node{
+				&argh.Command{
+					Name:   "prog",
+					Values: map[string]string{"0": "c"},
+					Nodes: []argh.Node{
+						&argh.Flag{
+							Name:   "files",
+							Values: map[string]string{"0": "a", "1": "b"},
+							Nodes: []argh.Node{
+								&argh.Ident{Literal: "a"},
+								&argh.Ident{Literal: "b"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "a", Pos: argh.Position{Column: 14}},
+								{Name: "1", Literal: "b", Pos: argh.Position{Column: 16}, Group: 1},
+							},
+						},
+						&argh.Ident{Literal: "c"},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "c", Pos: argh.Position{Column: 20}},
+					},
+				},
+			},
+		},
+		{
+			name: "strict unknown command",
+			args: []string{"prog", "stauts"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Commands: &argh.Commands{
+						Map: map[string]argh.CommandConfig{
+							"start":  {On: traceOnCommand},
+							"status": {On: traceOnCommand},
+						},
+					},
+					On: traceOnCommand,
+				},
+				Strict: true,
+			},
+			expErr: argh.ParserErrorList{
+				&argh.ParserError{
+					Pos: argh.Position{Column: 11},
+					Msg: "unknown command \"stauts\" (valid commands: start, status)",
+				},
+			},
+			expPT: []argh.Node{},
+		},
+		{
+			name: "strict excess positional",
+			args: []string{"prog", "a", "b", "--ok", "c"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: 1,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"ok": {On: traceOnFlag},
+						},
+					},
+					On: traceOnCommand,
+				},
+				Strict: true,
+			},
+			expErr: argh.ParserErrorList{
+				&argh.ParserError{Pos: argh.Position{Column: 8}, Msg: "unexpected argument \"b\""},
+				&argh.ParserError{Pos: argh.Position{Column: 15}, Msg: "unexpected argument \"c\""},
+			},
+			expPT: []argh.Node{},
+		},
+		{
+			name: "flag arity range",
+			args: []string{"prog", "--hosts", "a", "b", "c"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					NValue: 1,
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"hosts": {
+								Arity:      &argh.Arity{Min: 1, Max: 2},
+								ValueNames: []string{"host"},
+							},
+						},
+					},
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name:   "prog",
+					Values: map[string]string{"0": "c"},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "hosts",
+							Values: map[string]string{"host": "a", "host.1": "b"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "a"},
+								&argh.ArgDelimiter{},
+								&argh.Ident{Literal: "b"},
+							},
+							ValueList: []argh.Value{
+								{Name: "host", Literal: "a", Pos: argh.Position{Column: 14}},
+								{Name: "host.1", Literal: "b", Pos: argh.Position{Column: 16}, Group: 1},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "c"},
+					},
+					ValueList: []argh.Value{
+						{Name: "0", Literal: "c", Pos: argh.Position{Column: 18}},
+					},
+				},
+			},
+		},
+		{
+			name: "flag with too few values",
+			args: []string{"prog", "--pair", "a", "--ok", "--pair"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"pair": {NValue: 2},
+							"ok":   {},
+						},
+					},
+				},
+			},
+			expErr: argh.ParserErrorList{
+				&argh.ParserError{Pos: argh.Position{Column: 11}, Msg: "flag \"pair\" expects 2 values, got 1"},
+				&argh.ParserError{Pos: argh.Position{Column: 25}, Msg: "flag \"pair\" expects 2 values, got 0"},
+			},
+			expPT: []argh.Node{},
+		},
+		{
+			name: "command with too few positionals",
+			args: []string{"prog", "a", "sub"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Arity: &argh.Arity{Min: 2, Max: -1},
+					Commands: &argh.Commands{
+						Map: map[string]argh.CommandConfig{
+							"sub": {NValue: 1},
+						},
+					},
+				},
+			},
+			expErr: argh.ParserErrorList{
+				&argh.ParserError{Pos: argh.Position{Column: 4}, Msg: "command \"prog\" expects at least 2 positional arguments, got 1"},
+				&argh.ParserError{Pos: argh.Position{Column: 10}, Msg: "command \"sub\" expects 1 positional argument, got 0"},
+			},
+			expPT: []argh.Node{},
+		},
+		{
+			name: "variadic positional with trailing fixed positional",
+			args: []string{"cp", "a", "b", "c", "dir"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Positionals: []argh.PositionalConfig{
+						{Name: "src", Variadic: true},
+						{Name: "dst"},
+					},
+					On: traceOnCommand,
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "cp",
+					Values: map[string]string{
+						"src":   "a",
+						"src.1": "b",
+						"src.2": "c",
+						"dst":   "dir",
+					},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "a"},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "b"},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "c"},
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "dir"},
+					},
+					ValueList: []argh.Value{
+						{Name: "src", Literal: "a", Pos: argh.Position{Column: 4}},
+						{Name: "src.1", Literal: "b", Pos: argh.Position{Column: 6}, Group: 1},
+						{Name: "src.2", Literal: "c", Pos: argh.Position{Column: 8}, Group: 2},
+						{Name: "dst", Literal: "dir", Pos: argh.Position{Column: 12}, Group: 3},
+					},
+				},
+			},
+		},
+		{
+			name: "variadic positional missing trailing fixed positional",
+			args: []string{"cp", "a"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Positionals: []argh.PositionalConfig{
+						{Name: "src", Variadic: true},
+						{Name: "dst"},
+					},
+				},
+			},
+			expErr: argh.ParserErrorList{
+				&argh.ParserError{Pos: argh.Position{Column: 2}, Msg: "command \"cp\" expects at least 2 positional arguments, got 1"},
+			},
+			expPT: []argh.Node{},
+		},
+		{
+			name: "optional positional with variadic positional after stop flag",
+			args: []string{"git", "main", "--", "a", "b"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Positionals: []argh.PositionalConfig{
+						{Name: "tree-ish", Optional: true},
+						{Name: "paths", Variadic: true},
+					},
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "git",
+					Values: map[string]string{
+						"tree-ish": "main",
+						"paths":    "a",
+						"paths.1":  "b",
+					},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Ident{Literal: "main"},
+						&argh.ArgDelimiter{},
+						&argh.StopFlag{},
+						&argh.ArgDelimiter{},
+						&argh.PassthroughArgs{
+							Nodes: []argh.Node{
+								&argh.Ident{Literal: "a"},
+								&argh.Ident{Literal: "b"},
+							},
+						},
+					},
+					ValueList: []argh.Value{
+						{Name: "tree-ish", Literal: "main", Pos: argh.Position{Column: 8}},
+						{Name: "paths", Literal: "a", Pos: argh.Position{Column: 13}, Group: 1},
+						{Name: "paths.1", Literal: "b", Pos: argh.Position{Column: 15}, Group: 2},
+					},
+				},
+			},
+		},
+		{
+			name: "variadic positional only after stop flag",
+			args: []string{"git", "--", "-a"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Positionals: []argh.PositionalConfig{
+						{Name: "tree-ish", Optional: true},
+						{Name: "paths", Variadic: true},
+					},
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name:   "git",
+					Values: map[string]string{"paths": "-a"},
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.StopFlag{},
+						&argh.ArgDelimiter{},
+						&argh.PassthroughArgs{
+							Nodes: []argh.Node{
+								&argh.Ident{Literal: "-a"},
+							},
+						},
+					},
+					ValueList: []argh.Value{
+						{Name: "paths", Literal: "-a", Pos: argh.Position{Column: 9}},
+					},
+				},
+			},
+		},
+		{
+			name: "key=value flags",
+			args: []string{"prog", "-vDfoo=bar", "--label", "a=b,c=d", "--label=e=f"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"v":     {},
+							"D":     {KeyValue: true},
+							"label": {KeyValue: true},
+						},
+					},
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "prog",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.CompoundShortFlag{
+							Nodes: []argh.Node{
+								&argh.Flag{Name: "v"},
+								&argh.Flag{
+									Name:   "D",
+									Values: map[string]string{"foo": "bar"},
+									Nodes:  []argh.Node{&argh.Ident{Literal: "foo=bar"}},
+									Pairs:  []argh.Pair{{Key: "foo", Value: "bar"}},
+									ValueList: []argh.Value{
+										{Name: "foo", Literal: "bar", Pos: argh.Position{Column: 15}, Source: argh.ValueFromAttached},
+									},
+								},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "label",
+							Values: map[string]string{"a": "b", "c": "d"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.MultiIdent{
+									Nodes: []argh.Node{
+										&argh.Ident{Literal: "a=b"},
+										&argh.Ident{Literal: "c=d"},
+									},
+								},
+							},
+							Pairs: []argh.Pair{{Key: "a", Value: "b"}, {Key: "c", Value: "d"}},
+							ValueList: []argh.Value{
+								{Name: "a", Literal: "b", Pos: argh.Position{Column: 27}},
+								{Name: "c", Literal: "d", Pos: argh.Position{Column: 31}},
+							},
+						},
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "label",
+							Values: map[string]string{"e": "f"},
+							Nodes: []argh.Node{
+								&argh.Assign{},
+								&argh.Ident{Literal: "e=f"},
+							},
+							Pairs: []argh.Pair{{Key: "e", Value: "f"}},
+							ValueList: []argh.Value{
+								{Name: "e", Literal: "f", Pos: argh.Position{Column: 43}, Source: argh.ValueFromAssign},
+							},
+						},
+					},
+					Merged: map[string]argh.MergedFlag{
+						"D": {
+							Name:   "D",
+							Count:  1,
+							Values: []string{"foo=bar"},
+							Pairs:  []argh.Pair{{Key: "foo", Value: "bar"}},
+						},
+						"label": {
+							Name:   "label",
+							Count:  2,
+							Values: []string{"a=b", "c=d", "e=f"},
+							Pairs: []argh.Pair{
+								{Key: "a", Value: "b"},
+								{Key: "c", Value: "d"},
+								{Key: "e", Value: "f"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "malformed key=value pairs",
+			args: []string{"prog", "-D", "foo,=bar", "-D"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"D": {KeyValue: true},
+						},
+					},
+				},
+			},
+			expErr: argh.ParserErrorList{
+				&argh.ParserError{Pos: argh.Position{Column: 17}, Msg: "invalid key=value pair \"foo\" for flag \"D\""},
+				&argh.ParserError{Pos: argh.Position{Column: 17}, Msg: "invalid key=value pair \"=bar\" for flag \"D\""},
+				&argh.ParserError{Pos: argh.Position{Column: 19}, Msg: "flag \"D\" expects at least 1 value, got 0"},
+			},
+			expPT: []argh.Node{},
+		},
+		{
+			name: "per-flag multi-value delimiters",
+			args: []string{"prog", "--path", "/a:/b,c", "--query=SELECT a, b=1"},
+			cfg: &argh.ParserConfig{
+				Prog: &argh.CommandConfig{
+					Flags: &argh.Flags{
+						Map: map[string]argh.FlagConfig{
+							"path":  {NValue: argh.OneOrMoreValue, MultiValueDelim: ':'},
+							"query": {NValue: 1, MultiValueDelim: argh.NoMultiValueDelim},
+						},
+					},
+				},
+			},
+			expPT: []argh.Node{
+				&argh.Command{
+					Name: "prog",
+					Nodes: []argh.Node{
+						&argh.ArgDelimiter{},
+						&argh.Flag{
+							Name:   "path",
+							Values: map[string]string{"0": "/a", "1": "/b,c"},
+							Nodes: []argh.Node{
+								&argh.ArgDelimiter{},
+								&argh.MultiIdent{
+									Nodes: []argh.Node{
+										&argh.Ident{Literal: "/a"},
+										&argh.Ident{Literal: "/b,c"},
+									},
+									Delim: ':',
+								},
+								&argh.ArgDelimiter{},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "/a", Pos: argh.Position{Column: 14}},
+								{Name: "1", Literal: "/b,c", Pos: argh.Position{Column: 19}},
+							},
+						},
+						&argh.Flag{
+							Name:   "query",
+							Values: map[string]string{"0": "SELECT a, b=1"},
+							Nodes: []argh.Node{
+								&argh.Assign{},
+								&argh.Ident{Literal: "SELECT a, b=1"},
+							},
+							ValueList: []argh.Value{
+								{Name: "0", Literal: "SELECT a, b=1", Pos: argh.Position{Column: 41}, Source: argh.ValueFromAssign},
+							},
+						},
+					},
+				},
+			},
+		},
+	} {
+		if tc.expPT != nil {
+			t.Run(tc.name+" parse tree", func(ct *testing.T) {
+				if tc.skip {
+					ct.SkipNow()
+					return
+				}
+
+				pCfg := tc.cfg
+				if pCfg == nil {
+					pCfg = argh.NewParserConfig()
+				}
+
+				pt, err := argh.ParseArgs(tc.args, pCfg)
+				if err != nil || tc.expErr != nil {
+					if !assert.ErrorIs(ct, err, tc.expErr) {
+						spew.Dump(err, tc.expErr)
+						spew.Dump(pt)
+					}
+					return
+				}
+
+				if !assert.Equal(ct, tc.expPT, pt.Nodes) {
+					spew.Dump(pt)
+				}
+			})
+		}
+
+		if tc.expAST != nil {
+			t.Run(tc.name+" ast", func(ct *testing.T) {
+				if tc.skip {
+					ct.SkipNow()
+					return
+				}
+
+				pCfg := tc.cfg
+				if pCfg == nil {
+					pCfg = argh.NewParserConfig()
+				}
+
+				pt, err := argh.ParseArgs(tc.args, pCfg)
+				if err != nil || tc.expErr != nil {
+					if !assert.ErrorIs(ct, err, tc.expErr) {
+						spew.Dump(pt)
+					}
+					return
+				}
+
+				ast := argh.ToAST(pt.Nodes)
+
+				if !assert.Equal(ct, tc.expAST, ast) {
+					spew.Dump(ast)
+				}
+			})
+		}
+	}
+}
+
+func TestParserRepeatedFlags(t *testing.T) {
+	newCfg := func() *argh.ParserConfig {
+		pCfg := argh.NewParserConfig()
+
+		pCfg.Prog.SetFlagConfig("v", &argh.FlagConfig{Persist: true, Negatable: true, Repeat: argh.RepeatCount})
+		pCfg.Prog.SetFlagConfig("q", &argh.FlagConfig{Persist: true, Repeat: argh.RepeatCount})
+		pCfg.Prog.SetFlagConfig("tag", &argh.FlagConfig{Persist: true, NValue: argh.OneOrMoreValue, Repeat: argh.RepeatAppend})
+		pCfg.Prog.SetFlagConfig("level", &argh.FlagConfig{NValue: 1, Repeat: argh.RepeatLastWins})
+		pCfg.Prog.SetFlagConfig("once", &argh.FlagConfig{Repeat: argh.RepeatError})
+		pCfg.Prog.SetFlagConfig("each", &argh.FlagConfig{})
+
+		sub := &argh.CommandConfig{}
+		sub.SetFlagConfig("w", &argh.FlagConfig{Repeat: argh.RepeatCount})
+
+		pCfg.Prog.SetCommandConfig("sub", sub)
+
+		return pCfg
+	}
+
+	t.Run("merged", func(t *testing.T) {
+		r := require.New(t)
+
+		pt, err := argh.ParseArgs(
+			[]string{
+				"prog", "-vvq", "--tag", "a", "--level", "1", "--tag=b,c", "--each", "--level", "2", "--each",
+				"sub", "-wvw", "--tag", "d", "-q",
+			},
+			newCfg(),
+		)
+		r.NoError(err)
+
+		prog := pt.Nodes[0].(*argh.Command)
+		r.Equal(
+			map[string]argh.MergedFlag{
+				"v":     {Name: "v", Count: 3, Values: []string{"true"}},
+				"q":     {Name: "q", Count: 2},
+				"tag":   {Name: "tag", Count: 3, Values: []string{"a", "b", "c", "d"}},
+				"level": {Name: "level", Count: 2, Values: []string{"2"}},
+			},
+			prog.Merged,
+		)
+
+		sub := prog.Nodes[len(prog.Nodes)-1].(*argh.Command)
+		r.Equal(
+			map[string]argh.MergedFlag{
+				"w": {Name: "w", Count: 2},
+			},
+			sub.Merged,
+		)
+	})
+
+	t.Run("count reset by negation", func(t *testing.T) {
+		r := require.New(t)
+
+		pt, err := argh.ParseArgs([]string{"prog", "-vv", "--no-v", "-v"}, newCfg())
+		r.NoError(err)
+
+		r.Equal(
+			map[string]argh.MergedFlag{
+				"v": {Name: "v", Count: 1, Values: []string{"true"}},
+			},
+			pt.Nodes[0].(*argh.Command).Merged,
+		)
+	})
+
+	t.Run("error on repeat", func(t *testing.T) {
+		r := require.New(t)
+
+		_, err := argh.ParseArgs([]string{"prog", "--once", "-v", "--once"}, newCfg())
+		r.ErrorIs(
+			err,
+			argh.ParserErrorList{
+				&argh.ParserError{Pos: argh.Position{Column: 21}, Msg: "flag \"once\" given more than once"},
+			},
+		)
+	})
 }
@@ -0,0 +1,113 @@
+package argh
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ActionFunc is the type of the Action, Before and After hooks of a
+// CommandConfig.
+type ActionFunc func(ctx context.Context, inv *Invocation) error
+
+// Result is the outcome of Run.
+type Result struct {
+	// Tree is the parse tree of the command line, which is nil when
+	// parsing failed before completing.
+	Tree *ParseTree
+
+	// Invocation is the resolved invocation, which is nil when
+	// parsing failed.
+	Invocation *Invocation
+
+	// ActionCalled is whether the Action of the invoked command was
+	// called, regardless of whether it succeeded.
+	ActionCalled bool
+}
+
+// Run parses the given args and, if successful, calls the Action of
+// the invoked command along with the Before and After hooks of the
+// commands on the path to it. Errors returned by the Action and hooks
+// are wrapped in a ParserError with the ErrAction code.
+func Run(ctx context.Context, args []string, pCfg *ParserConfig) (*Result, error) {
+	pt, err := ParseArgs(args, pCfg)
+
+	return run(ctx, pCfg, pt, err)
+}
+
+// Run parses and runs the given args in the same way as Run.
+func (cp *CompiledParser) Run(ctx context.Context, args []string) (*Result, error) {
+	pt, err := cp.Parse(args)
+
+	return run(ctx, cp.cfg, pt, err)
+}
+
+func run(ctx context.Context, pCfg *ParserConfig, pt *ParseTree, err error) (*Result, error) {
+	res := &Result{Tree: pt}
+
+	if err != nil {
+		return res, err
+	}
+
+	inv := resolveInvocation(pt, pCfg)
+	res.Invocation = inv
+
+	cat := pCfg.catalogue()
+	cCfg := inv.Config()
+
+	if cCfg.Action == nil {
+		return res, &ParserError{
+			Msg:           cat.ErrorMessage(ErrNoAction, strings.Join(inv.Path, " ")),
+			Code:          ErrNoAction,
+			Node:          inv.Command(),
+			Path:          inv.Path,
+			CommandConfig: cCfg,
+		}
+	}
+
+	actionError := func(stage string, i int, err error) error {
+		return &ParserError{
+			Msg:           cat.ErrorMessage(ErrAction, stage, strings.Join(inv.Path[:i+1], " "), err),
+			Code:          ErrAction,
+			Node:          inv.Commands[i],
+			Path:          inv.Path[:i+1],
+			CommandConfig: inv.Configs[i],
+			Err:           err,
+		}
+	}
+
+	entered := 0
+
+	for i, cCfg := range inv.Configs {
+		if cCfg.Before != nil {
+			if beforeErr := cCfg.Before(ctx, inv); beforeErr != nil {
+				err = actionError("before", i, beforeErr)
+				break
+			}
+		}
+
+		entered = i + 1
+	}
+
+	if err == nil {
+		res.ActionCalled = true
+
+		if actionErr := cCfg.Action(ctx, inv); actionErr != nil {
+			err = actionError("action", len(inv.Configs)-1, actionErr)
+		}
+	}
+
+	for i := entered - 1; i >= 0; i-- {
+		if after := inv.Configs[i].After; after != nil {
+			if afterErr := after(ctx, inv); afterErr != nil {
+				if err == nil {
+					err = actionError("after", i, afterErr)
+				} else {
+					err = errors.Join(err, actionError("after", i, afterErr))
+				}
+			}
+		}
+	}
+
+	return res, err
+}
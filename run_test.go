@@ -0,0 +1,197 @@
+package argh_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+type ctxKey struct{}
+
+func TestRun(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type failures struct {
+		before, action, after string
+	}
+
+	newCfg := func(calls *[]string, fail failures) *argh.ParserConfig {
+		hooks := func(name string) (argh.ActionFunc, argh.ActionFunc, argh.ActionFunc) {
+			hook := func(stage string) argh.ActionFunc {
+				return func(ctx context.Context, inv *argh.Invocation) error {
+					*calls = append(*calls, stage+" "+name)
+
+					if ctx.Value(ctxKey{}) != "value" {
+						return errors.New("missing context value")
+					}
+
+					if map[string]string{"before": fail.before, "action": fail.action, "after": fail.after}[stage] == name {
+						return errBoom
+					}
+
+					return nil
+				}
+			}
+
+			return hook("before"), hook("action"), hook("after")
+		}
+
+		pCfg := argh.NewParserConfig()
+		pCfg.Prog.Before, pCfg.Prog.Action, pCfg.Prog.After = hooks("prog")
+		pCfg.Prog.SetFlagConfig("v", &argh.FlagConfig{Persist: true})
+
+		sub := &argh.CommandConfig{NValue: 1}
+		sub.Before, sub.Action, sub.After = hooks("sub")
+
+		leaf := &argh.CommandConfig{}
+		leaf.Before, leaf.Action, _ = hooks("leaf")
+		sub.SetCommandConfig("leaf", leaf)
+
+		pCfg.Prog.SetCommandConfig("sub", sub)
+		pCfg.Prog.SetCommandConfig("noop", &argh.CommandConfig{})
+
+		return pCfg
+	}
+
+	for _, tc := range []struct {
+		name        string
+		args        []string
+		fail        failures
+		expCalls    []string
+		expPath     []string
+		expCalled   bool
+		expCode     argh.ErrorCode
+		expErr      string
+		expNoInv    bool
+		expWrapsErr bool
+	}{
+		{
+			name:      "program",
+			args:      []string{"prog", "-v"},
+			expCalls:  []string{"before prog", "action prog", "after prog"},
+			expPath:   []string{"prog"},
+			expCalled: true,
+		},
+		{
+			name: "nested",
+			args: []string{"prog", "sub", "x", "-v", "leaf"},
+			expCalls: []string{
+				"before prog", "before sub", "before leaf",
+				"action leaf",
+				"after sub", "after prog",
+			},
+			expPath:   []string{"prog", "sub", "leaf"},
+			expCalled: true,
+		},
+		{
+			name:        "action error",
+			args:        []string{"prog", "sub", "x"},
+			fail:        failures{action: "sub"},
+			expCalls:    []string{"before prog", "before sub", "action sub", "after sub", "after prog"},
+			expPath:     []string{"prog", "sub"},
+			expCalled:   true,
+			expCode:     argh.ErrAction,
+			expErr:      "command \"prog sub\" action: boom",
+			expWrapsErr: true,
+		},
+		{
+			name:        "before error",
+			args:        []string{"prog", "sub", "x", "leaf"},
+			fail:        failures{before: "sub"},
+			expCalls:    []string{"before prog", "before sub", "after prog"},
+			expPath:     []string{"prog", "sub", "leaf"},
+			expCode:     argh.ErrAction,
+			expErr:      "command \"prog sub\" before: boom",
+			expWrapsErr: true,
+		},
+		{
+			name:        "after error",
+			args:        []string{"prog", "sub", "x", "leaf"},
+			fail:        failures{after: "prog"},
+			expCalls:    []string{"before prog", "before sub", "before leaf", "action leaf", "after sub", "after prog"},
+			expPath:     []string{"prog", "sub", "leaf"},
+			expCalled:   true,
+			expCode:     argh.ErrAction,
+			expErr:      "command \"prog\" after: boom",
+			expWrapsErr: true,
+		},
+		{
+			name:     "no action",
+			args:     []string{"prog", "noop"},
+			expCalls: []string{},
+			expPath:  []string{"prog", "noop"},
+			expCode:  argh.ErrNoAction,
+			expErr:   "command \"prog noop\" has no action",
+		},
+		{
+			name:     "parse error",
+			args:     []string{"prog", "sub"},
+			expCalls: []string{},
+			expCode:  argh.ErrMissingPositional,
+			expNoInv: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			calls := []string{}
+			ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+			res, err := argh.Run(ctx, tc.args, newCfg(&calls, tc.fail))
+			r.NotNil(res)
+			r.Equal(tc.expCalls, calls)
+			r.Equal(tc.expCalled, res.ActionCalled)
+
+			if tc.expCode != 0 {
+				r.ErrorIs(err, tc.expCode)
+			} else {
+				r.NoError(err)
+			}
+
+			if tc.expErr != "" {
+				r.EqualError(err, tc.expErr)
+			}
+
+			if tc.expWrapsErr {
+				r.ErrorIs(err, errBoom)
+			}
+
+			if tc.expNoInv {
+				r.Nil(res.Invocation)
+				return
+			}
+
+			r.Equal(tc.expPath, res.Invocation.Path)
+			r.Len(res.Invocation.Commands, len(tc.expPath))
+			r.Equal(tc.expPath[len(tc.expPath)-1], res.Invocation.Command().Name)
+		})
+	}
+}
+
+func TestCompiledParserRun(t *testing.T) {
+	r := require.New(t)
+
+	var got []string
+
+	pCfg := argh.NewParserConfig()
+	pCfg.Prog.SetCommandConfig("greet", &argh.CommandConfig{
+		NValue:     1,
+		ValueNames: []string{"name"},
+		Action: func(_ context.Context, inv *argh.Invocation) error {
+			got = append(got, inv.Command().Values["name"])
+			return nil
+		},
+	})
+
+	cp, err := argh.Compile(pCfg)
+	r.NoError(err)
+
+	res, err := cp.Run(context.Background(), []string{"prog", "greet", "world"})
+	r.NoError(err)
+	r.True(res.ActionCalled)
+	r.Equal([]string{"world"}, got)
+	r.NotNil(res.Invocation.Config().Action)
+}
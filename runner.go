@@ -0,0 +1,132 @@
+package argh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The default exit codes of a Runner, following sysexits.h where it
+// applies.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+
+	// ExitUsage is EX_USAGE, for command lines that do not parse or
+	// do not invoke a command with an Action.
+	ExitUsage = 64
+
+	// ExitSoftware is EX_SOFTWARE, for errors that are not caused by
+	// the command line, such as an invalid ParserConfig.
+	ExitSoftware = 70
+)
+
+// ExitCoder may be implemented by errors returned from an Action or
+// hook, or a flag or command handler, to choose the exit code of a
+// Runner.
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// Runner runs a command line with the policy most programs want:
+// errors are written via PrintParserError, optionally along with the
+// usage of the relevant command, and mapped to an exit code.
+type Runner struct {
+	// Stderr receives errors and usage, which is os.Stderr when nil.
+	Stderr io.Writer
+
+	// ExitCodes overrides the exit codes of the given error codes.
+	ExitCodes map[ErrorCode]int
+
+	// PrintUsage enables writing the usage of the command for which
+	// the first error was reported after usage errors.
+	PrintUsage bool
+}
+
+// Run runs the given args as by Run, returning the exit code for the
+// outcome.
+func (rn *Runner) Run(ctx context.Context, args []string, pCfg *ParserConfig) int {
+	res, err := Run(ctx, args, pCfg)
+	if err == nil {
+		return ExitOK
+	}
+
+	w := rn.Stderr
+	if w == nil {
+		w = os.Stderr
+	}
+
+	PrintParserError(w, err)
+
+	if rn.PrintUsage && isUsageError(err) {
+		path := args[:min(len(args), 1)]
+
+		var pErr *ParserError
+		if errors.As(err, &pErr) && len(pErr.Path) > 0 {
+			path = pErr.Path
+		} else if res.Tree != nil && len(res.Tree.Nodes) > 0 {
+			path = resolveInvocation(res.Tree, pCfg).Path
+		}
+
+		if len(path) > 0 {
+			// NOTE: the program is named as invoked, which may be
+			// a full path that does not belong in usage.
+			path = append([]string{filepath.Base(path[0])}, path[1:]...)
+
+			fmt.Fprintln(w)
+			_ = WriteUsage(w, pCfg, path)
+		}
+	}
+
+	return rn.ExitCode(err)
+}
+
+// isUsageError returns whether the given error is caused by the
+// command line itself rather than by a handler or Action.
+func isUsageError(err error) bool {
+	var pErr *ParserError
+	if !errors.As(err, &pErr) {
+		return false
+	}
+
+	return pErr.Code != 0 && pErr.Code != ErrHandler && pErr.Code != ErrAction
+}
+
+// ExitCode returns the exit code for the given error, which is that
+// of the first ExitCoder it wraps, if any, otherwise that of the code
+// of the first ParserError it wraps.
+func (rn *Runner) ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitCoder ExitCoder
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+
+	var pErr *ParserError
+	if errors.As(err, &pErr) {
+		return rn.exitCodeOf(pErr.Code)
+	}
+
+	return ExitSoftware
+}
+
+func (rn *Runner) exitCodeOf(code ErrorCode) int {
+	if exitCode, ok := rn.ExitCodes[code]; ok {
+		return exitCode
+	}
+
+	switch code {
+	case ErrHandler, ErrAction:
+		return ExitFailure
+	case 0:
+		return ExitSoftware
+	}
+
+	return ExitUsage
+}
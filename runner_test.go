@@ -0,0 +1,184 @@
+package argh_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string { return "exit" }
+
+func (e exitError) ExitCode() int { return e.code }
+
+func TestRunner(t *testing.T) {
+	newCfg := func() *argh.ParserConfig {
+		pCfg := argh.NewParserConfig()
+		pCfg.Prog.SetFlagConfig("v", &argh.FlagConfig{Persist: true, Usage: "Be verbose."})
+		pCfg.Prog.SetFlagConfig("boom", &argh.FlagConfig{
+			On: func(argh.Flag) error { return errors.New("boom") },
+		})
+
+		pCfg.Prog.SetCommandConfig("ok", &argh.CommandConfig{
+			Action: func(context.Context, *argh.Invocation) error { return nil },
+		})
+		pCfg.Prog.SetCommandConfig("fail", &argh.CommandConfig{
+			Action: func(context.Context, *argh.Invocation) error { return errors.New("nope") },
+		})
+		pCfg.Prog.SetCommandConfig("exit", &argh.CommandConfig{
+			Action: func(context.Context, *argh.Invocation) error { return exitError{code: 3} },
+		})
+		pCfg.Prog.SetCommandConfig("cp", &argh.CommandConfig{
+			NValue:     2,
+			ValueNames: []string{"src", "dst"},
+			Action:     func(context.Context, *argh.Invocation) error { return nil },
+		})
+
+		return pCfg
+	}
+
+	for _, tc := range []struct {
+		name      string
+		args      []string
+		runner    argh.Runner
+		cfg       *argh.ParserConfig
+		expCode   int
+		expStderr string
+	}{
+		{
+			name:    "ok",
+			args:    []string{"prog", "ok"},
+			expCode: argh.ExitOK,
+		},
+		{
+			name:      "action error",
+			args:      []string{"prog", "fail"},
+			expCode:   argh.ExitFailure,
+			expStderr: "command \"prog fail\" action: nope\n",
+		},
+		{
+			name:      "handler error",
+			args:      []string{"prog", "--boom"},
+			expCode:   argh.ExitFailure,
+			expStderr: "11:flag \"boom\" handler: boom\n",
+		},
+		{
+			name:      "exit coder",
+			args:      []string{"prog", "exit"},
+			expCode:   3,
+			expStderr: "command \"prog exit\" action: exit\n",
+		},
+		{
+			name:      "usage error",
+			args:      []string{"prog", "cp", "a"},
+			expCode:   argh.ExitUsage,
+			expStderr: "7:command \"cp\" expects 2 positional arguments, got 1\n",
+		},
+		{
+			name:    "usage error with usage",
+			args:    []string{"prog", "cp", "a"},
+			runner:  argh.Runner{PrintUsage: true},
+			expCode: argh.ExitUsage,
+			expStderr: strings.Join([]string{
+				"7:command \"cp\" expects 2 positional arguments, got 1",
+				"",
+				"usage: prog cp [flags] <src> <dst>",
+				"",
+				"flags:",
+				"  -v  Be verbose.",
+				"",
+			}, "\n"),
+		},
+		{
+			name:    "usage error with program path",
+			args:    []string{"/usr/local/bin/prog", "cp", "a"},
+			runner:  argh.Runner{PrintUsage: true},
+			expCode: argh.ExitUsage,
+			expStderr: strings.Join([]string{
+				"22:command \"cp\" expects 2 positional arguments, got 1",
+				"",
+				"usage: prog cp [flags] <src> <dst>",
+				"",
+				"flags:",
+				"  -v  Be verbose.",
+				"",
+			}, "\n"),
+		},
+		{
+			name:    "unknown flag with command usage",
+			args:    []string{"prog", "cp", "--nope"},
+			runner:  argh.Runner{PrintUsage: true},
+			expCode: argh.ExitUsage,
+			expStderr: strings.Join([]string{
+				"14:unknown flag \"nope\"",
+				"",
+				"usage: prog cp [flags] <src> <dst>",
+				"",
+				"flags:",
+				"  -v  Be verbose.",
+				"",
+			}, "\n"),
+		},
+		{
+			name:    "unknown flag with program usage",
+			args:    []string{"prog", "--nope", "cp"},
+			runner:  argh.Runner{PrintUsage: true},
+			expCode: argh.ExitUsage,
+			expStderr: strings.Join([]string{
+				"11:unknown flag \"nope\"",
+				"",
+				"usage: prog [flags] <command>",
+				"",
+				"commands:",
+				"  cp",
+				"  exit",
+				"  fail",
+				"  ok",
+				"",
+				"flags:",
+				"  --boom",
+				"  -v      Be verbose.",
+				"",
+			}, "\n"),
+		},
+		{
+			name:      "no action",
+			args:      []string{"prog"},
+			runner:    argh.Runner{ExitCodes: map[argh.ErrorCode]int{argh.ErrNoAction: 2}},
+			expCode:   2,
+			expStderr: "command \"prog\" has no action\n",
+		},
+		{
+			name:      "invalid config",
+			args:      []string{"prog"},
+			cfg:       &argh.ParserConfig{},
+			runner:    argh.Runner{PrintUsage: true},
+			expCode:   argh.ExitSoftware,
+			expStderr: "nil program command config: urfave/argh error\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			stderr := &strings.Builder{}
+
+			rn := tc.runner
+			rn.Stderr = stderr
+
+			pCfg := tc.cfg
+			if pCfg == nil {
+				pCfg = newCfg()
+			}
+
+			r.Equal(tc.expCode, rn.Run(context.Background(), tc.args, pCfg))
+			r.Equal(tc.expStderr, stderr.String())
+		})
+	}
+}
@@ -1,31 +1,75 @@
 package argh
 
 import (
-	"bufio"
-	"bytes"
-	"errors"
 	"io"
-	"log"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+const (
+	argDelimiterLiteral = string(nul)
+)
+
+// Scanner scans tokens from a sequence of args, returning literals
+// that are substrings of the args so that scanning does not
+// allocate.
 type Scanner struct {
-	r   *bufio.Reader
+	args []string
+	cfg  *ScannerConfig
+
+	// arg is the index of the current arg and off is the byte offset
+	// within it, while i is the rune position across all args
+	// including the ARG_DELIMITER between each.
+	arg int
+	off int
 	i   int
-	cfg *ScannerConfig
+
+	prevArg int
+	prevOff int
+
+	err error
 }
 
+// NewScanner returns a Scanner over the args read from r, which are
+// expected to be separated by NUL. If reading from r fails, the
+// Scanner scans the args read until then and the error is returned
+// by Err.
 func NewScanner(r io.Reader, cfg *ScannerConfig) *Scanner {
+	b, err := io.ReadAll(r)
+
+	s := NewArgsScanner(strings.Split(string(b), argDelimiterLiteral), cfg)
+	s.err = err
+
+	return s
+}
+
+// NewArgsScanner returns a Scanner over the given args.
+func NewArgsScanner(args []string, cfg *ScannerConfig) *Scanner {
+	s := &Scanner{}
+	s.Reset(args, cfg)
+
+	return s
+}
+
+// Reset prepares the Scanner to scan the given args from the start.
+func (s *Scanner) Reset(args []string, cfg *ScannerConfig) {
 	if cfg == nil {
 		cfg = POSIXyScannerConfig
 	}
 
-	return &Scanner{
-		r:   bufio.NewReader(r),
-		cfg: cfg,
+	*s = Scanner{
+		args: args,
+		cfg:  cfg,
 	}
 }
 
+// Err returns the error encountered when reading the args of a
+// Scanner created by NewScanner, if any.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
 func (s *Scanner) Scan() (Token, string, Pos) {
 	ch, pos := s.read()
 
@@ -35,11 +79,11 @@ func (s *Scanner) Scan() (Token, string, Pos) {
 	}
 
 	if s.cfg.IsAssignmentOperator(ch) {
-		return ASSIGN, string(ch), pos
+		return ASSIGN, s.lastLiteral(), pos
 	}
 
 	if s.cfg.IsMultiValueDelim(ch) {
-		return MULTI_VALUE_DELIMITER, string(ch), pos
+		return MULTI_VALUE_DELIMITER, s.lastLiteral(), pos
 	}
 
 	if ch == eol {
@@ -47,7 +91,7 @@ func (s *Scanner) Scan() (Token, string, Pos) {
 	}
 
 	if ch == nul {
-		return ARG_DELIMITER, string(ch), pos
+		return ARG_DELIMITER, argDelimiterLiteral, pos
 	}
 
 	if unicode.IsGraphic(ch) {
@@ -55,33 +99,48 @@ func (s *Scanner) Scan() (Token, string, Pos) {
 		return s.scanArg()
 	}
 
-	return ILLEGAL, string(ch), pos
+	return ILLEGAL, s.lastLiteral(), pos
 }
 
 func (s *Scanner) read() (rune, Pos) {
-	ch, _, err := s.r.ReadRune()
+	s.prevArg, s.prevOff = s.arg, s.off
 	s.i++
 
-	if errors.Is(err, io.EOF) {
-		return eol, Pos(s.i)
-	} else if err != nil {
-		log.Printf("unknown scanner error=%+v", err)
+	if s.arg >= len(s.args) {
 		return eol, Pos(s.i)
 	}
 
+	if s.off >= len(s.args[s.arg]) {
+		if s.arg == len(s.args)-1 {
+			return eol, Pos(s.i)
+		}
+
+		s.arg++
+		s.off = 0
+
+		return nul, Pos(s.i)
+	}
+
+	ch, size := utf8.DecodeRuneInString(s.args[s.arg][s.off:])
+	s.off += size
+
 	return ch, Pos(s.i)
 }
 
 func (s *Scanner) unread() Pos {
-	_ = s.r.UnreadRune()
+	s.arg, s.off = s.prevArg, s.prevOff
 	s.i--
 	return Pos(s.i)
 }
 
+// lastLiteral returns the rune last read within the current arg.
+func (s *Scanner) lastLiteral() string {
+	return s.args[s.arg][s.prevOff:s.off]
+}
+
 func (s *Scanner) scanBlankspace() (Token, string, Pos) {
-	buf := &bytes.Buffer{}
 	ch, pos := s.read()
-	buf.WriteRune(ch)
+	start := s.prevOff
 
 	for {
 		ch, pos = s.read()
@@ -91,18 +150,15 @@ func (s *Scanner) scanBlankspace() (Token, string, Pos) {
 		} else if !s.cfg.IsBlankspace(ch) {
 			pos = s.unread()
 			break
-		} else {
-			_, _ = buf.WriteRune(ch)
 		}
 	}
 
-	return BS, buf.String(), pos
+	return BS, s.args[s.arg][start:s.off], pos
 }
 
 func (s *Scanner) scanArg() (Token, string, Pos) {
-	buf := &bytes.Buffer{}
 	ch, pos := s.read()
-	buf.WriteRune(ch)
+	start := s.prevOff
 
 	for {
 		ch, pos = s.read()
@@ -111,11 +167,9 @@ func (s *Scanner) scanArg() (Token, string, Pos) {
 			pos = s.unread()
 			break
 		}
-
-		_, _ = buf.WriteRune(ch)
 	}
 
-	str := buf.String()
+	str := s.args[s.arg][start:s.off]
 
 	if len(str) == 0 {
 		return EMPTY, str, pos
@@ -1,5 +1,7 @@
 package argh
 
+import "strconv"
+
 var (
 	// POSIXyScannerConfig defines a scanner config that uses '-'
 	// as the flag prefix, which also means that "--" is the "long
@@ -30,6 +32,27 @@ func (cfg *ScannerConfig) IsAssignmentOperator(ch rune) bool {
 	return ch == cfg.AssignmentOperator
 }
 
+// IsNegativeNumber returns whether the given literal is a number
+// with the flag prefix as its sign, e.g. "-42" or "-3.5", which is
+// only possible when the flag prefix is '-'.
+func (cfg *ScannerConfig) IsNegativeNumber(lit string) bool {
+	if cfg.FlagPrefix != '-' || len(lit) < 2 || !cfg.IsFlagPrefix(rune(lit[0])) {
+		return false
+	}
+
+	digits := lit[1:]
+	if digits[0] == '.' {
+		digits = digits[1:]
+	}
+
+	if len(digits) == 0 || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+
+	_, err := strconv.ParseFloat(lit[1:], 64)
+	return err == nil
+}
+
 func (cfg *ScannerConfig) IsBlankspace(ch rune) bool {
 	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
@@ -0,0 +1,44 @@
+package argh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScannerConfigIsNegativeNumber(t *testing.T) {
+	windowsyScannerConfig := &ScannerConfig{
+		AssignmentOperator: ':',
+		FlagPrefix:         '/',
+		MultiValueDelim:    ',',
+	}
+
+	for _, tc := range []struct {
+		lit string
+		cfg *ScannerConfig
+		exp bool
+	}{
+		{lit: "-42", exp: true},
+		{lit: "-3.5", exp: true},
+		{lit: "-.5", exp: true},
+		{lit: "-1e3", exp: true},
+		{lit: "-0", exp: true},
+		{lit: "-", exp: false},
+		{lit: "--", exp: false},
+		{lit: "-.", exp: false},
+		{lit: "-inf", exp: false},
+		{lit: "-NaN", exp: false},
+		{lit: "-4x2", exp: false},
+		{lit: "42", exp: false},
+		{lit: "/42", cfg: windowsyScannerConfig, exp: false},
+	} {
+		t.Run(tc.lit, func(t *testing.T) {
+			cfg := tc.cfg
+			if cfg == nil {
+				cfg = POSIXyScannerConfig
+			}
+
+			require.Equal(t, tc.exp, cfg.IsNegativeNumber(tc.lit))
+		})
+	}
+}
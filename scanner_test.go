@@ -1,8 +1,10 @@
 package argh
 
 import (
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -81,3 +83,27 @@ func TestScannerPOSIXyScanner(t *testing.T) {
 		})
 	}
 }
+
+func TestScannerErr(t *testing.T) {
+	r := require.New(t)
+
+	scanner := NewScanner(io.MultiReader(
+		strings.NewReader(strings.Join([]string{"walrus", "-what"}, string(nul))),
+		iotest.ErrReader(io.ErrUnexpectedEOF),
+	), nil)
+
+	r.ErrorIs(scanner.Err(), io.ErrUnexpectedEOF)
+
+	literals := []string{}
+
+	for {
+		tok, lit, _ := scanner.Scan()
+		if tok == EOL {
+			break
+		}
+
+		literals = append(literals, lit)
+	}
+
+	r.Equal([]string{"walrus", argDelimiterLiteral, "-what"}, literals)
+}
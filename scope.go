@@ -0,0 +1,211 @@
+package argh
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Scope is the command being parsed, as given to the Enter and Exit
+// hooks of its config and to the Configure hooks of flags given to
+// it, through which further flags and sub-commands may be configured
+// for the remainder of the command line. Such changes only apply to
+// the current parse, leaving the ParserConfig unchanged so that it
+// may be reused or compiled.
+type Scope struct {
+	p    *Parser
+	cCfg *CommandConfig
+}
+
+// SetFlagConfig configures the named flag for the command, taking
+// precedence over any flag of the same name in its config. Persistent
+// flags also apply to sub-commands parsed afterwards.
+func (s *Scope) SetFlagConfig(name string, flCfg *FlagConfig) {
+	if s.p.scopedFlags == nil {
+		s.p.scopedFlags = map[*Flags]map[string]FlagConfig{}
+	}
+
+	flags := s.p.scopedFlags[s.cCfg.Flags]
+	if flags == nil {
+		flags = map[string]FlagConfig{}
+		s.p.scopedFlags[s.cCfg.Flags] = flags
+	}
+
+	flags[name] = *flCfg
+}
+
+// SetCommandConfig configures the named sub-command for the command,
+// taking precedence over any sub-command of the same name in its
+// config. The given config is copied, so that it is left unchanged
+// and may be shared between parses.
+func (s *Scope) SetCommandConfig(name string, sCfg *CommandConfig) {
+	if s.p.scopedCommands == nil {
+		s.p.scopedCommands = map[*CommandConfig]map[string]CommandConfig{}
+	}
+
+	commands := s.p.scopedCommands[s.cCfg]
+	if commands == nil {
+		commands = map[string]CommandConfig{}
+		s.p.scopedCommands[s.cCfg] = commands
+	}
+
+	cfg := *sCfg
+	cfg.init()
+	cfg.Flags = s.p.reparentFlags(cfg.Flags, s.cCfg.Flags)
+
+	commands[name] = cfg
+}
+
+// reparentFlags returns a copy of the given Flags with the given
+// Parent for the current parse, which is also the Parent of the
+// flags of its own sub-commands when they are looked up.
+func (p *Parser) reparentFlags(fl, parent *Flags) *Flags {
+	if p.scopedParents == nil {
+		p.scopedParents = map[*Flags]*Flags{}
+	}
+
+	ret := *fl
+	ret.Parent = parent
+
+	// NOTE: the index of a compiled Flags includes the persistent
+	// flags of its original Parent, so that the Map and Parent are
+	// consulted instead.
+	ret.index = nil
+
+	p.scopedParents[fl] = &ret
+
+	return &ret
+}
+
+// scoped returns whether anything has been configured via a Scope
+// during the current parse.
+func (p *Parser) scoped() bool {
+	return p.scopedFlags != nil || p.scopedCommands != nil
+}
+
+// recordCommand records the config with which the given command is
+// parsed, as it may not be found in the ParserConfig once anything
+// has been configured via a Scope.
+func (p *Parser) recordCommand(node *Command, cCfg *CommandConfig) {
+	if p.commandConfigs == nil {
+		p.commandConfigs = map[*Command]*CommandConfig{}
+	}
+
+	p.commandConfigs[node] = cCfg
+}
+
+// recordFlag records the config with which the given flag was
+// parsed in the same way as recordCommand.
+func (p *Parser) recordFlag(cCfg *CommandConfig, node *Flag, flCfg FlagConfig) {
+	if p.flagConfigs == nil {
+		p.flagConfigs = map[*Flag]indexedFlag{}
+	}
+
+	_, owner, _ := p.lookupFlagOwner(cCfg.Flags, node.Name)
+
+	p.flagConfigs[node] = indexedFlag{flCfg: flCfg, owner: owner}
+}
+
+func (p *Parser) lookupCommand(cCfg *CommandConfig, name string) (CommandConfig, bool) {
+	subCfg, ok := p.scopedCommands[cCfg][name]
+	if !ok {
+		subCfg, ok = cCfg.GetCommandConfig(name)
+
+		if ok && p.scopedParents != nil && subCfg.Flags != nil && subCfg.Flags.Parent != nil &&
+			p.scopedParents[subCfg.Flags.Parent] == cCfg.Flags {
+			subCfg.Flags = p.reparentFlags(subCfg.Flags, cCfg.Flags)
+		}
+	}
+
+	if p.eventsOn {
+		p.event(TraceLookup, slog.String("kind", "command"), slog.String("name", name), slog.Bool("found", ok))
+	}
+
+	return subCfg, ok
+}
+
+func (p *Parser) lookupFlag(cCfg *CommandConfig, name string) (FlagConfig, bool) {
+	flCfg, _, ok := p.lookupFlagOwner(cCfg.Flags, name)
+
+	if p.eventsOn {
+		p.event(TraceLookup, slog.String("kind", "flag"), slog.String("name", name), slog.Bool("found", ok))
+	}
+
+	return flCfg, ok
+}
+
+// lookupNegatedFlag returns the config of the Negatable flag for
+// which the given name is the negated spelling, if any.
+func (p *Parser) lookupNegatedFlag(cCfg *CommandConfig, name string) (string, FlagConfig, bool) {
+	posName, flCfg, ok := "", FlagConfig{}, false
+
+	// NOTE: a flag configured with the negated spelling of a
+	// Negatable flag takes precedence over the negation.
+	if strings.HasPrefix(name, negatedFlagPrefix) && !p.isConfiguredFlag(cCfg.Flags, name) {
+		posName = strings.TrimPrefix(name, negatedFlagPrefix)
+		flCfg, _, ok = p.lookupFlagOwner(cCfg.Flags, posName)
+		ok = ok && flCfg.Negatable
+	}
+
+	if p.eventsOn {
+		p.event(TraceLookup, slog.String("kind", "negated flag"), slog.String("name", name), slog.Bool("found", ok))
+	}
+
+	if !ok {
+		return "", FlagConfig{}, false
+	}
+
+	return posName, flCfg, true
+}
+
+// isConfiguredFlag returns whether the named flag is configured
+// rather than only accepted as an Automatic flag.
+func (p *Parser) isConfiguredFlag(fl *Flags, name string) bool {
+	_, _, ok := p.lookupConfiguredFlag(fl, name)
+	return ok
+}
+
+// lookupFlagOwner is like Flags.lookup, but also considers the flags
+// configured via a Scope, including persistent flags of ancestors.
+func (p *Parser) lookupFlagOwner(fl *Flags, name string) (FlagConfig, *Flags, bool) {
+	if p.scopedFlags == nil {
+		return fl.lookup(name)
+	}
+
+	if flCfg, owner, ok := p.lookupConfiguredFlag(fl, name); ok {
+		return flCfg, owner, true
+	}
+
+	if fl != nil && fl.Automatic {
+		return FlagConfig{}, fl, true
+	}
+
+	return FlagConfig{}, nil, false
+}
+
+// lookupConfiguredFlag is like lookupFlagOwner, but without accepting
+// Automatic flags in the same way as Flags.lookupConfigured.
+func (p *Parser) lookupConfiguredFlag(fl *Flags, name string) (FlagConfig, *Flags, bool) {
+	if p.scopedFlags == nil {
+		return fl.lookupConfigured(name)
+	}
+
+	if flCfg, ok := p.scopedFlags[fl][name]; ok {
+		return flCfg, fl, true
+	}
+
+	if flCfg, owner, ok := fl.lookupConfigured(name); ok {
+		return flCfg, owner, true
+	}
+
+	if fl == nil || fl.Automatic {
+		return FlagConfig{}, nil, false
+	}
+
+	for cur := fl.Parent; cur != nil; cur = cur.Parent {
+		if flCfg, ok := p.scopedFlags[cur][name]; ok && flCfg.Persist {
+			return flCfg, cur, true
+		}
+	}
+
+	return FlagConfig{}, nil, false
+}
@@ -0,0 +1,207 @@
+package argh_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+func newPluginParserConfig(calls *[]string) *argh.ParserConfig {
+	record := func(call string) func(*argh.Scope) error {
+		return func(*argh.Scope) error {
+			*calls = append(*calls, call)
+			return nil
+		}
+	}
+
+	pCfg := argh.NewParserConfig()
+	pCfg.Prog.Enter = record("enter prog")
+	pCfg.Prog.Exit = record("exit prog")
+	pCfg.Prog.On = func(argh.Command) error {
+		*calls = append(*calls, "on prog")
+		return nil
+	}
+
+	pCfg.Prog.SetFlagConfig("plugin", &argh.FlagConfig{
+		NValue: 1,
+		Configure: func(fl argh.Flag, scope *argh.Scope) error {
+			*calls = append(*calls, "configure "+fl.Values["0"])
+
+			scope.SetFlagConfig(fl.Values["0"]+"-opt", &argh.FlagConfig{NValue: 1, Persist: true, Repeat: argh.RepeatAppend})
+			scope.SetFlagConfig(fl.Values["0"]+"-color", &argh.FlagConfig{Negatable: true})
+			scope.SetCommandConfig(fl.Values["0"], &argh.CommandConfig{
+				NValue: 1,
+				Enter:  record("enter " + fl.Values["0"]),
+				Exit:   record("exit " + fl.Values["0"]),
+			})
+
+			return nil
+		},
+	})
+
+	return pCfg
+}
+
+func TestScope(t *testing.T) {
+	args := []string{"prog", "--plugin", "foo", "--no-foo-color", "--foo-opt", "1", "foo", "--foo-opt", "2", "x"}
+
+	t.Run("configured for remaining args", func(t *testing.T) {
+		r := require.New(t)
+
+		calls := []string{}
+		pCfg := newPluginParserConfig(&calls)
+
+		pt, err := argh.ParseArgs(args, pCfg)
+		r.NoError(err)
+
+		r.Equal(
+			[]string{"enter prog", "configure foo", "enter foo", "exit foo", "exit prog", "on prog"},
+			calls,
+		)
+
+		prog := pt.Nodes[0].(*argh.Command)
+		r.Equal([]string{"1", "2"}, prog.Merged["foo-opt"].Values)
+
+		color, ok := prog.Bool("foo-color")
+		r.True(ok)
+		r.False(color)
+
+		foo := prog.Nodes[len(prog.Nodes)-1].(*argh.Command)
+		r.Equal("foo", foo.Name)
+		r.Equal(map[string]string{"0": "x"}, foo.Values)
+
+		_, ok = pCfg.Prog.GetFlagConfig("foo-opt")
+		r.False(ok, "the parser config is unchanged")
+
+		_, err = argh.ParseArgs([]string{"prog", "--foo-opt", "1"}, pCfg)
+		r.ErrorIs(err, argh.ErrUnknownFlag)
+	})
+
+	t.Run("compiled", func(t *testing.T) {
+		r := require.New(t)
+
+		calls := []string{}
+
+		cp, err := argh.Compile(newPluginParserConfig(&calls))
+		r.NoError(err)
+
+		for i := 0; i < 2; i++ {
+			pt, err := cp.Parse(args)
+			r.NoError(err)
+
+			prog := pt.Nodes[0].(*argh.Command)
+			r.Equal(2, prog.Merged["foo-opt"].Count)
+		}
+
+		pt, err := cp.Parse([]string{"prog", "foo"})
+		r.NoError(err)
+		r.Equal([]argh.Node{&argh.ArgDelimiter{}, &argh.Ident{Literal: "foo"}}, pt.Nodes[0].(*argh.Command).Nodes)
+	})
+
+	t.Run("shared command config", func(t *testing.T) {
+		r := require.New(t)
+
+		target := &argh.CommandConfig{}
+		target.SetFlagConfig("force", &argh.FlagConfig{})
+
+		deploy := &argh.CommandConfig{NValue: 1, Flags: &argh.Flags{}}
+		deploy.SetCommandConfig("target", target)
+
+		pCfg := argh.NewParserConfig()
+		pCfg.Prog.SetFlagConfig("v", &argh.FlagConfig{Persist: true})
+		pCfg.Prog.SetFlagConfig("plugin", &argh.FlagConfig{
+			NValue: 1,
+			Configure: func(_ argh.Flag, scope *argh.Scope) error {
+				scope.SetCommandConfig("deploy", deploy)
+				return nil
+			},
+		})
+
+		cp, err := argh.Compile(pCfg)
+		r.NoError(err)
+
+		wg := sync.WaitGroup{}
+		errs := make(chan error, 8)
+
+		for i := 0; i < cap(errs); i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := cp.Parse([]string{"prog", "--plugin", "x", "deploy", "t", "-v", "target", "-v", "--force"})
+				errs <- err
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			r.NoError(err)
+		}
+
+		r.Nil(deploy.Flags.Parent, "the command config is unchanged")
+	})
+
+	t.Run("run", func(t *testing.T) {
+		r := require.New(t)
+
+		var invoked *argh.Invocation
+
+		pCfg := argh.NewParserConfig()
+		pCfg.Prog.Action = func(context.Context, *argh.Invocation) error {
+			return errors.New("prog invoked")
+		}
+		pCfg.Prog.SetFlagConfig("plugin", &argh.FlagConfig{
+			NValue: 1,
+			Configure: func(_ argh.Flag, scope *argh.Scope) error {
+				scope.SetFlagConfig("region", &argh.FlagConfig{NValue: 1, Persist: true})
+				scope.SetCommandConfig("deploy", &argh.CommandConfig{
+					Positionals: []argh.PositionalConfig{{Name: "target"}},
+					Action: func(_ context.Context, inv *argh.Invocation) error {
+						invoked = inv
+						return nil
+					},
+				})
+
+				return nil
+			},
+		})
+
+		res, err := argh.Run(context.Background(), []string{"prog", "--plugin", "x", "deploy", "tgt", "--region", "eu"}, pCfg)
+		r.NoError(err)
+		r.True(res.ActionCalled)
+
+		r.NotNil(invoked)
+		r.Equal([]string{"prog", "deploy"}, invoked.Path)
+
+		target, ok := invoked.Positional("target")
+		r.True(ok)
+		r.Equal("tgt", target)
+
+		region, ok := invoked.Flag("region")
+		r.True(ok)
+		r.True(region.Config.Persist)
+		r.Equal(1, region.Level)
+		r.Equal(0, region.Owner)
+		r.Equal("eu", region.Flag.Values["0"])
+	})
+
+	t.Run("hook error", func(t *testing.T) {
+		r := require.New(t)
+
+		errBoom := errors.New("boom")
+
+		pCfg := argh.NewParserConfig()
+		pCfg.Prog.Enter = func(*argh.Scope) error { return errBoom }
+
+		_, err := argh.ParseArgs([]string{"prog"}, pCfg)
+		r.ErrorIs(err, argh.ErrHandler)
+		r.ErrorIs(err, errBoom)
+	})
+}
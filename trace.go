@@ -0,0 +1,72 @@
+package argh
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// LevelTrace is the level below slog.LevelDebug at which a Parser
+// logs the details of its internal state, which are mostly of
+// interest when debugging argh itself.
+const LevelTrace = slog.LevelDebug - 4
+
+// The messages of the structured events a Parser logs at
+// slog.LevelDebug to the ParserConfig.Logger.
+const (
+	// TraceScan is logged for every token scanned, with the "tok",
+	// "lit" and "pos" attributes.
+	TraceScan = "argh.scan"
+
+	// TraceLookup is logged for every flag or command config lookup,
+	// with the "kind", "name" and "found" attributes.
+	TraceLookup = "argh.lookup"
+
+	// TraceNode is logged for every node added to a command, with the
+	// "type" and "node" attributes.
+	TraceNode = "argh.node"
+
+	// TraceHandler is logged before calling a flag or command config
+	// handler, with the "kind", "hook" and "name" attributes, where
+	// hook is the name of the config field such as "On".
+	TraceHandler = "argh.handler"
+)
+
+// resetTracing determines which levels are enabled on the configured
+// Logger, if any, so that disabled tracing costs no more than a
+// boolean check.
+func (p *Parser) resetTracing() {
+	p.log, p.eventsOn, p.traceOn = nil, false, false
+
+	if p.cfg == nil || p.cfg.Logger == nil {
+		return
+	}
+
+	p.log = p.cfg.Logger
+	p.eventsOn = p.log.Enabled(context.Background(), slog.LevelDebug)
+	p.traceOn = p.log.Enabled(context.Background(), LevelTrace)
+}
+
+func (p *Parser) event(msg string, attrs ...slog.Attr) {
+	p.log.LogAttrs(context.Background(), slog.LevelDebug, msg, attrs...)
+}
+
+func (p *Parser) tracef(format string, a ...any) {
+	p.log.LogAttrs(context.Background(), LevelTrace, fmt.Sprintf(format, a...))
+}
+
+func (p *Parser) appendNode(nodes []Node, node Node) []Node {
+	if p.eventsOn {
+		p.event(
+			TraceNode,
+			slog.String("type", fmt.Sprintf("%T", node)),
+			slog.Any("node", node),
+		)
+	}
+
+	return append(nodes, node)
+}
+
+func (p *Parser) handlerEvent(kind, hook, name string) {
+	p.event(TraceHandler, slog.String("kind", kind), slog.String("hook", hook), slog.String("name", name))
+}
@@ -0,0 +1,113 @@
+package argh_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+// recordingHandler is a slog.Handler that keeps the message and
+// attributes of every record at or above its level.
+type recordingHandler struct {
+	level slog.Level
+
+	mu      sync.Mutex
+	records []string
+	attrs   []map[string]any
+}
+
+func (h *recordingHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *recordingHandler) Handle(_ context.Context, rec slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	attrs := map[string]any{}
+
+	rec.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.Any()
+		return true
+	})
+
+	h.records = append(h.records, rec.Message)
+	h.attrs = append(h.attrs, attrs)
+
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordingHandler) WithGroup(string) slog.Handler { return h }
+
+func TestParserLogger(t *testing.T) {
+	r := require.New(t)
+
+	h := &recordingHandler{level: slog.LevelDebug}
+
+	pCfg := argh.NewParserConfig()
+	pCfg.Logger = slog.New(h)
+	pCfg.Prog.SetFlagConfig("v", &argh.FlagConfig{
+		On: func(argh.Flag) error { return nil },
+	})
+	pCfg.Prog.SetCommandConfig("sub", &argh.CommandConfig{})
+
+	_, err := argh.ParseArgs([]string{"prog", "-v", "sub"}, pCfg)
+	r.NoError(err)
+
+	r.Equal(
+		[]string{
+			argh.TraceScan,
+			argh.TraceScan,
+			argh.TraceLookup,
+			argh.TraceNode,
+			argh.TraceScan,
+			argh.TraceLookup,
+			argh.TraceLookup,
+			argh.TraceHandler,
+			argh.TraceNode,
+			argh.TraceScan,
+			argh.TraceLookup,
+			argh.TraceNode,
+			argh.TraceScan,
+			argh.TraceLookup,
+			argh.TraceScan,
+			argh.TraceLookup,
+			argh.TraceNode,
+		},
+		h.records,
+	)
+
+	r.Equal(map[string]any{"tok": "IDENT", "lit": "prog", "pos": int64(4)}, h.attrs[0])
+	r.Equal(map[string]any{"kind": "flag", "name": "v", "found": true}, h.attrs[6])
+	r.Equal(map[string]any{"kind": "flag", "hook": "On", "name": "v"}, h.attrs[7])
+	r.Equal(map[string]any{"kind": "command", "name": "sub", "found": true}, h.attrs[13])
+	r.Equal("*argh.Command", h.attrs[len(h.attrs)-1]["type"])
+
+	t.Run("disabled level", func(t *testing.T) {
+		r := require.New(t)
+
+		h := &recordingHandler{level: slog.LevelInfo}
+		pCfg.Logger = slog.New(h)
+
+		_, err := argh.ParseArgs([]string{"prog", "-v", "sub"}, pCfg)
+		r.NoError(err)
+		r.Empty(h.records)
+	})
+
+	t.Run("trace level", func(t *testing.T) {
+		r := require.New(t)
+
+		h := &recordingHandler{level: argh.LevelTrace}
+		pCfg.Logger = slog.New(h)
+
+		_, err := argh.ParseArgs([]string{"prog", "-v", "sub"}, pCfg)
+		r.NoError(err)
+		r.Contains(h.records, "parseCommand(...) breaking after sub-command=sub")
+	})
+}
@@ -11,9 +11,7 @@ import (
 func UnparseTree(nodes []Node, cfg *ScannerConfig) ([]string, error) {
 	buf := []string{}
 
-	for i, node := range nodes {
-		tracef("Unparse i=%[1]d node type=%[2]T", i, node)
-
+	for _, node := range nodes {
 		switch v := node.(type) {
 		case *ArgDelimiter:
 			continue
@@ -29,6 +27,12 @@ func UnparseTree(nodes []Node, cfg *ScannerConfig) ([]string, error) {
 		case *Ident:
 			buf = append(buf, v.Literal)
 			continue
+		case *UnknownFlag:
+			buf = append(buf, v.Literal)
+			continue
+		case *ListTerminator:
+			buf = append(buf, v.Literal)
+			continue
 		case *PassthroughArgs:
 			sv, err := UnparseTree(v.Nodes, cfg)
 			if err != nil {
@@ -40,17 +44,27 @@ func UnparseTree(nodes []Node, cfg *ScannerConfig) ([]string, error) {
 		case *CompoundShortFlag:
 			if v.Nodes != nil {
 				flagStrings := []string{}
-
-				sv, err := UnparseTree(v.Nodes, cfg)
-				if err != nil {
-					return buf, err
-				}
-
-				for _, flagString := range sv {
-					flagStrings = append(flagStrings, strings.TrimPrefix(flagString, string(cfg.FlagPrefix)))
+				tail := []string{}
+
+				for _, flagNode := range v.Nodes {
+					sv, err := UnparseTree([]Node{flagNode}, cfg)
+					if err != nil {
+						return buf, err
+					}
+
+					if len(sv) == 0 {
+						continue
+					}
+
+					// NOTE: only the flag itself and any directly
+					// attached value belong in the compound group,
+					// while separate value arguments follow it.
+					flagStrings = append(flagStrings, strings.TrimPrefix(sv[0], string(cfg.FlagPrefix)))
+					tail = append(tail, sv[1:]...)
 				}
 
 				buf = append(buf, string(cfg.FlagPrefix)+strings.Join(flagStrings, ""))
+				buf = append(buf, tail...)
 			}
 
 			continue
@@ -61,7 +75,12 @@ func UnparseTree(nodes []Node, cfg *ScannerConfig) ([]string, error) {
 					return buf, err
 				}
 
-				buf = append(buf, strings.Join(sv, string(cfg.MultiValueDelim)))
+				delim := cfg.MultiValueDelim
+				if v.Delim != 0 {
+					delim = v.Delim
+				}
+
+				buf = append(buf, strings.Join(sv, string(delim)))
 			}
 
 			continue
@@ -80,14 +99,17 @@ func UnparseTree(nodes []Node, cfg *ScannerConfig) ([]string, error) {
 			buf = append(buf, sv...)
 			continue
 		case *Flag:
+			name := v.Name
+			if v.Negated {
+				name = negatedFlagPrefix + name
+			}
+
 			prefix := string(cfg.FlagPrefix)
-			if len(v.Name) > 1 {
+			if len(name) > 1 {
 				prefix += string(cfg.FlagPrefix)
 			}
 
-			flStr := prefix + v.Name
-
-			tracef("flag string=%[1]q", flStr)
+			flStr := prefix + name
 
 			if len(v.Nodes) > 0 {
 				flStr, tail, err := unParseFlagNodes(flStr, v.Nodes, cfg)
@@ -95,12 +117,8 @@ func UnparseTree(nodes []Node, cfg *ScannerConfig) ([]string, error) {
 					return buf, err
 				}
 
-				tracef("appending %[1]q with tail=%[2]q", flStr, tail)
-
 				buf = append(append(buf, flStr), tail...)
 			} else {
-				tracef("appending %[1]q", flStr)
-
 				buf = append(buf, flStr)
 			}
 
@@ -121,8 +139,6 @@ func unParseFlagNodes(flStr string, nodes []Node, cfg *ScannerConfig) (string, [
 	if _, ok := nodes[0].(*ArgDelimiter); ok {
 		tail, err := UnparseTree(nodes[1:], cfg)
 
-		tracef("explicit arg delimiter present; returning flag str=%[1]q tail=%[2]q", flStr, tail)
-
 		return flStr, tail, err
 	}
 
@@ -135,19 +151,13 @@ func unParseFlagNodes(flStr string, nodes []Node, cfg *ScannerConfig) (string, [
 		flStr = flStr + tail[0] + tail[1]
 		tail = tail[2:]
 
-		tracef("assign operator present with adjacent nodes; returning flag str=%[1]q tail=%[2]q", flStr, tail)
-
 		return flStr, tail, nil
 	} else if len(flStr) == 2 {
 		flStr = flStr + tail[0]
 		tail = tail[1:]
 
-		tracef("short flag detected; returning flag str=%[1]q tail=%[2]q", flStr, tail)
-
 		return flStr, tail, nil
 	}
 
-	tracef("no special cases; returning flag str=%[1]q tail=%[2]q", flStr, tail)
-
 	return flStr, tail, nil
 }
@@ -82,6 +82,28 @@ func TestUnparseTree(t *testing.T) {
 		)
 	})
 
+	t.Run("negated flags", func(t *testing.T) {
+		r := require.New(t)
+
+		sv, err := UnparseTree(
+			[]Node{
+				&Command{
+					Name: "pizzas",
+					Nodes: []Node{
+						&ArgDelimiter{},
+						&Flag{Name: "cheese", Negated: true, Values: map[string]string{"0": "false"}},
+						&ArgDelimiter{},
+						&Flag{Name: "sauce", Values: map[string]string{"0": "true"}},
+					},
+				},
+			},
+			POSIXyScannerConfig,
+		)
+
+		r.NoError(err)
+		r.Equal([]string{"pizzas", "--no-cheese", "--sauce"}, sv)
+	})
+
 	t.Run("simple", func(t *testing.T) {
 		r := require.New(t)
 
@@ -163,6 +185,51 @@ func TestUnparseTree(t *testing.T) {
 		r.Equal([]string{"howling", "-fRiEnd=o"}, sv)
 	})
 
+	t.Run("compound flags with attached and separate values", func(t *testing.T) {
+		r := require.New(t)
+
+		sv, err := UnparseTree(
+			[]Node{
+				&Command{
+					Name: "tar",
+					Nodes: []Node{
+						&ArgDelimiter{},
+						&CompoundShortFlag{
+							Nodes: []Node{
+								&Flag{Name: "x"},
+								&Flag{
+									Name:   "f",
+									Values: map[string]string{"0": "archive.tar"},
+									Nodes: []Node{
+										&Ident{Literal: "archive.tar"},
+									},
+								},
+							},
+						},
+						&ArgDelimiter{},
+						&CompoundShortFlag{
+							Nodes: []Node{
+								&Flag{Name: "v"},
+								&Flag{
+									Name:   "C",
+									Values: map[string]string{"0": "dir"},
+									Nodes: []Node{
+										&ArgDelimiter{},
+										&Ident{Literal: "dir"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			POSIXyScannerConfig,
+		)
+
+		r.NoError(err)
+		r.Equal([]string{"tar", "-xfarchive.tar", "-vC", "dir"}, sv)
+	})
+
 	t.Run("multi-value flags", func(t *testing.T) {
 		r := require.New(t)
 
@@ -455,4 +522,74 @@ func TestUnparseTree(t *testing.T) {
 			sv,
 		)
 	})
+
+	t.Run("round trip", func(t *testing.T) {
+		pCfg := NewParserConfig()
+		pCfg.Prog.SetFlagConfig("v", &FlagConfig{})
+		pCfg.Prog.SetFlagConfig("o", &FlagConfig{NValue: 1})
+
+		pCfg.Prog.SetFlagConfig("files", &FlagConfig{NValue: OneOrMoreValue, Terminator: ";"})
+		pCfg.Prog.SetFlagConfig("D", &FlagConfig{KeyValue: true})
+		pCfg.Prog.SetFlagConfig("label", &FlagConfig{KeyValue: true})
+		pCfg.Prog.SetFlagConfig("I", &FlagConfig{NValue: 1, MultiValueDelim: ':'})
+		pCfg.Prog.SetFlagConfig("query", &FlagConfig{NValue: 1, MultiValueDelim: NoMultiValueDelim})
+		pCfg.Prog.Flags.PassthroughUnknown = true
+
+		exec := &CommandConfig{NValue: 1}
+		exec.SetFlagConfig("i", &FlagConfig{})
+		exec.Flags.PassthroughUnknown = true
+
+		pCfg.Prog.SetCommandConfig("exec", exec)
+
+		for _, args := range [][]string{
+			{"kubectl", "-vofile.txt", "exec", "pod", "-i", "--", "sh", "-c", "x=1,2", "--", ""},
+			{"kubectl", "-vo", "file.txt", "exec", "pod", "--"},
+			{"kubectl", "--context=prod", "-vw", "exec", "pod", "--tty", "-it"},
+			{"kubectl", "-vwo=1,2", "exec", "pod", "-iw", "x"},
+			{"kubectl", "--files", "a", "b", ";", "exec", "pod"},
+			{"kubectl", "-vDfoo=bar", "--label", "a=b,c=d", "--label=e=f", "-D", "x=", "exec", "pod"},
+			{"kubectl", "-vI/a:/b,c", "-I", "/d", "--query=SELECT a, b=1", "--query", "x,y", "exec", "pod"},
+		} {
+			r := require.New(t)
+
+			pt, err := ParseArgs(args, pCfg)
+			r.NoError(err)
+
+			sv, err := UnparseTree(pt.Nodes, POSIXyScannerConfig)
+			r.NoError(err)
+			r.Equal(args, sv)
+		}
+
+		pt, err := ParseArgs([]string{"kubectl", "-vw"}, pCfg)
+		require.NoError(t, err)
+		require.Equal(t, []Node{&Flag{Name: "v"}, &UnknownFlag{Literal: "-w"}}, ToAST(pt.Nodes[0].(*Command).Nodes))
+	})
+
+	t.Run("round trip via AST", func(t *testing.T) {
+		pCfg := NewParserConfig()
+
+		exec := &CommandConfig{NValue: 1}
+		exec.SetFlagConfig("i", &FlagConfig{})
+
+		pCfg.Prog.SetCommandConfig("exec", exec)
+
+		for _, args := range [][]string{
+			{"kubectl", "exec", "pod", "--", "sh", "-c", "x"},
+			{"kubectl", "exec", "pod", "-i", "--", "--", "-i"},
+			{"kubectl", "exec", "pod", "--"},
+		} {
+			r := require.New(t)
+
+			pt, err := ParseArgs(args, pCfg)
+			r.NoError(err)
+
+			sv, err := UnparseTree(ToAST(pt.Nodes), POSIXyScannerConfig)
+			r.NoError(err)
+			r.Equal(args, sv)
+
+			reparsed, err := ParseArgs(sv, pCfg)
+			r.NoError(err)
+			r.Equal(ToAST(pt.Nodes), ToAST(reparsed.Nodes))
+		}
+	})
 }
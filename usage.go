@@ -0,0 +1,212 @@
+package argh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// WriteUsage writes the usage of the command at the given path, which
+// is the program name followed by the names of any sub-commands, with
+// all text provided by the Catalogue of the ParserConfig.
+func WriteUsage(w io.Writer, pCfg *ParserConfig, path []string) error {
+	if pCfg == nil || pCfg.Prog == nil {
+		return fmt.Errorf("nil parser config: %w", Err)
+	}
+
+	if len(path) == 0 {
+		return fmt.Errorf("empty command path: %w", Err)
+	}
+
+	cCfg := pCfg.Prog
+
+	for _, name := range path[1:] {
+		sub, ok := cCfg.GetCommandConfig(name)
+		if !ok {
+			return fmt.Errorf("unknown command %[1]q: %[2]w", name, Err)
+		}
+
+		cCfg = &sub
+	}
+
+	sCfg := pCfg.ScannerConfig
+	if sCfg == nil {
+		sCfg = POSIXyScannerConfig
+	}
+
+	cat := pCfg.catalogue()
+	flags := usageFlags(cCfg.Flags)
+
+	var commands []string
+	if cCfg.Commands != nil {
+		commands = cCfg.Commands.Names()
+	}
+
+	line := []string{strings.Join(path, " ")}
+
+	if len(flags) > 0 {
+		line = append(line, cat.Text(TextFlagsPlaceholder))
+	}
+
+	if positionals := usagePositionals(cCfg, cat); positionals != "" {
+		line = append(line, positionals)
+	}
+
+	if len(commands) > 0 {
+		line = append(line, cat.Text(TextCommandPlaceholder))
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, cat.Text(TextUsage, strings.Join(line, " ")))
+
+	if cCfg.Usage != "" {
+		fmt.Fprintf(tw, "\n%s\n", cat.Text(cCfg.Usage))
+	}
+
+	if len(commands) > 0 {
+		fmt.Fprintf(tw, "\n%s\n", cat.Text(TextCommands))
+
+		for _, name := range commands {
+			sub, _ := cCfg.Commands.Get(name)
+			fmt.Fprintf(tw, "  %s\t%s\n", name, usageText(cat, sub.Usage))
+		}
+	}
+
+	if len(flags) > 0 {
+		fmt.Fprintf(tw, "\n%s\n", cat.Text(TextFlags))
+
+		for _, name := range flags {
+			flCfg, _ := cCfg.Flags.Get(name)
+			fmt.Fprintf(tw, "  %s\t%s\n", usageFlag(name, flCfg, sCfg, cat), usageText(cat, flCfg.Usage))
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// NOTE: the padding of rows without usage text is trimmed.
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if _, err := io.WriteString(w, strings.TrimRight(line, " ")+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// usageFlags returns the sorted names of the flags that may be given,
+// including persistent flags of parent commands, but unlike
+// Flags.Names not the negated spellings of Negatable flags.
+func usageFlags(fl *Flags) []string {
+	seen := map[string]bool{}
+
+	for cur, persistOnly := fl, false; cur != nil; cur, persistOnly = cur.Parent, true {
+		for name, flCfg := range cur.Map {
+			if !persistOnly || flCfg.Persist {
+				seen[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func usageFlag(name string, flCfg FlagConfig, sCfg *ScannerConfig, cat Catalogue) string {
+	prefix := string(sCfg.FlagPrefix)
+	if utf8.RuneCountInString(name) > 1 {
+		prefix += prefix
+	}
+
+	if flCfg.Negatable {
+		name = "[" + negatedFlagPrefix + "]" + name
+	}
+
+	values := usagePlaceholders(flCfg.ValueNames, flCfg.arity(), cat.Text(TextValueName))
+
+	switch {
+	case values == "":
+		return prefix + name
+	case flCfg.AttachedValue:
+		return prefix + name + "[" + string(sCfg.AssignmentOperator) + values + "]"
+	}
+
+	return prefix + name + " " + values
+}
+
+func usagePositionals(cCfg *CommandConfig, cat Catalogue) string {
+	if len(cCfg.Positionals) == 0 {
+		return usagePlaceholders(cCfg.ValueNames, cCfg.arity(), cat.Text(TextArgName))
+	}
+
+	slots := make([]string, 0, len(cCfg.Positionals))
+
+	for _, posCfg := range cCfg.Positionals {
+		slot := "<" + posCfg.Name + ">"
+
+		if posCfg.Variadic {
+			slot += "..."
+		}
+
+		if posCfg.Optional {
+			slot = "[" + slot + "]"
+		}
+
+		slots = append(slots, slot)
+	}
+
+	return strings.Join(slots, " ")
+}
+
+// usagePlaceholders returns the placeholders of the values within the
+// given arity, named like the values themselves when parsed.
+func usagePlaceholders(names []string, arity Arity, fallback string) string {
+	name := func(i int) string {
+		if i < len(names) {
+			return names[i]
+		}
+
+		if len(names) == 1 {
+			return names[0]
+		}
+
+		return fallback
+	}
+
+	placeholders := []string{}
+
+	for i := 0; i < arity.Min; i++ {
+		placeholders = append(placeholders, "<"+name(i)+">")
+	}
+
+	for i := arity.Min; i < arity.Max; i++ {
+		placeholders = append(placeholders, "[<"+name(i)+">]")
+	}
+
+	if arity.Max < 0 {
+		placeholders = append(placeholders, "[<"+name(arity.Min)+">...]")
+	}
+
+	return strings.Join(placeholders, " ")
+}
+
+func usageText(cat Catalogue, text string) string {
+	if text == "" {
+		return ""
+	}
+
+	return cat.Text(text)
+}
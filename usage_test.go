@@ -0,0 +1,132 @@
+package argh_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/argh"
+)
+
+func newUsageParserConfig() *argh.ParserConfig {
+	pCfg := argh.NewParserConfig()
+	pCfg.Prog.Usage = "Copies and removes things."
+	pCfg.Prog.SetFlagConfig("v", &argh.FlagConfig{Persist: true, Usage: "Be verbose."})
+	pCfg.Prog.SetFlagConfig("color", &argh.FlagConfig{Negatable: true, Persist: true, Usage: "Colorize output."})
+	pCfg.Prog.SetFlagConfig("log", &argh.FlagConfig{AttachedValue: true, NValue: 1, ValueNames: []string{"level"}})
+	pCfg.Prog.SetFlagConfig("local", &argh.FlagConfig{Usage: "Not persisted."})
+
+	cp := &argh.CommandConfig{
+		Usage: "Copy things.",
+		Positionals: []argh.PositionalConfig{
+			{Name: "src", Variadic: true},
+			{Name: "dst"},
+		},
+	}
+	cp.SetFlagConfig("D", &argh.FlagConfig{KeyValue: true, Usage: "Set a property."})
+	cp.SetFlagConfig("n", &argh.FlagConfig{Arity: &argh.Arity{Min: 1, Max: 2}})
+
+	pCfg.Prog.SetCommandConfig("cp", cp)
+	pCfg.Prog.SetCommandConfig("rm", &argh.CommandConfig{NValue: argh.OneOrMoreValue, ValueNames: []string{"path"}})
+
+	return pCfg
+}
+
+func TestWriteUsage(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		path   []string
+		cat    argh.Catalogue
+		exp    string
+		expErr string
+	}{
+		{
+			name: "program",
+			path: []string{"prog"},
+			exp: `usage: prog [flags] <command>
+
+Copies and removes things.
+
+commands:
+  cp  Copy things.
+  rm
+
+flags:
+  --[no-]color     Colorize output.
+  --local          Not persisted.
+  --log[=<level>]
+  -v               Be verbose.
+`,
+		},
+		{
+			name: "sub-command",
+			path: []string{"prog", "cp"},
+			exp: `usage: prog cp [flags] <src>... <dst>
+
+Copy things.
+
+flags:
+  -D <value>            Set a property.
+  --[no-]color          Colorize output.
+  -n <value> [<value>]
+  -v                    Be verbose.
+`,
+		},
+		{
+			name: "repeating value name",
+			path: []string{"prog", "rm"},
+			exp: `usage: prog rm [flags] <path> [<path>...]
+
+flags:
+  --[no-]color  Colorize output.
+  -v            Be verbose.
+`,
+		},
+		{
+			name: "translated",
+			path: []string{"prog", "rm"},
+			cat: argh.MapCatalogue{
+				Texts: map[string]string{
+					argh.TextUsage:            "utilisation : %[1]s",
+					argh.TextFlags:            "options :",
+					argh.TextFlagsPlaceholder: "[options]",
+					"Be verbose.":             "Être verbeux.",
+				},
+			},
+			exp: `utilisation : prog rm [options] <path> [<path>...]
+
+options :
+  --[no-]color  Colorize output.
+  -v            Être verbeux.
+`,
+		},
+		{
+			name:   "unknown command",
+			path:   []string{"prog", "mv"},
+			expErr: "unknown command \"mv\"",
+		},
+		{
+			name:   "empty path",
+			expErr: "empty command path",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			pCfg := newUsageParserConfig()
+			pCfg.Catalogue = tc.cat
+
+			buf := &strings.Builder{}
+
+			err := argh.WriteUsage(buf, pCfg, tc.path)
+			if tc.expErr != "" {
+				r.ErrorIs(err, argh.Err)
+				r.ErrorContains(err, tc.expErr)
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tc.exp, buf.String())
+		})
+	}
+}